@@ -0,0 +1,148 @@
+package grocery
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CachingStore wraps an API and serves All from memory for TTL after each
+// fetch. Any write through the CachingStore drops the cached notes. When the
+// cache is empty or stale, concurrent All calls share a single fetch from
+// the wrapped store rather than each making their own; a caller whose ctx is
+// done stops waiting without cancelling the fetch for the others. The TTL is
+// measured on Clock, the wall clock when nil.
+type CachingStore struct {
+	Clock Clock
+
+	store API
+	ttl   time.Duration
+
+	mu       sync.Mutex
+	notes    []*Note
+	fetched  time.Time
+	valid    bool
+	gen      int
+	inflight *fetch
+}
+
+// fetch is an All call to the wrapped store that other callers can wait on.
+type fetch struct {
+	done  chan struct{}
+	notes []*Note
+	err   error
+}
+
+func NewCachingStore(store API, ttl time.Duration) *CachingStore {
+	return &CachingStore{store: store, ttl: ttl}
+}
+
+func (s *CachingStore) All(ctx context.Context) ([]*Note, error) {
+	s.mu.Lock()
+	if s.valid && clockOr(s.Clock).Now().Sub(s.fetched) < s.ttl {
+		notes := cloneNotes(s.notes)
+		s.mu.Unlock()
+		return notes, nil
+	}
+
+	f := s.inflight
+	if f == nil {
+		f = &fetch{done: make(chan struct{})}
+		s.inflight = f
+		go s.fetchAll(context.WithoutCancel(ctx), f, s.gen)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-f.done:
+		if f.err != nil {
+			return nil, f.err
+		}
+		return cloneNotes(f.notes), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetchAll runs f against the wrapped store and caches its notes unless a
+// write has invalidated the cache since gen. Its ctx isn't cancelled with
+// the caller that started it, so the callers still waiting get an answer.
+func (s *CachingStore) fetchAll(ctx context.Context, f *fetch, gen int) {
+	notes, err := s.store.All(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f.notes, f.err = notes, err
+	if err == nil && gen == s.gen {
+		s.notes = notes
+		s.fetched = clockOr(s.Clock).Now()
+		s.valid = true
+	}
+	s.inflight = nil
+	close(f.done)
+}
+
+func (s *CachingStore) Get(ctx context.Context, text string) (*Note, error) {
+	return s.store.Get(ctx, text)
+}
+
+func (s *CachingStore) GetMany(ctx context.Context, texts []string) (map[string]*Note, error) {
+	return s.store.GetMany(ctx, texts)
+}
+
+func (s *CachingStore) Count(ctx context.Context) (int, error) {
+	return countNotes(ctx, s.store)
+}
+
+func (s *CachingStore) Ping(ctx context.Context) error {
+	return s.store.Ping(ctx)
+}
+
+func (s *CachingStore) Close() error {
+	return closeStore(s.store)
+}
+
+func (s *CachingStore) Create(ctx context.Context, n *Note) error {
+	defer s.invalidate()
+	return s.store.Create(ctx, n)
+}
+
+func (s *CachingStore) CreateMany(ctx context.Context, notes []*Note) error {
+	defer s.invalidate()
+	return s.store.CreateMany(ctx, notes)
+}
+
+func (s *CachingStore) Update(ctx context.Context, n *Note) error {
+	defer s.invalidate()
+	return s.store.Update(ctx, n)
+}
+
+func (s *CachingStore) UpdateFields(ctx context.Context, id string, fields map[string]interface{}) error {
+	defer s.invalidate()
+	return s.store.UpdateFields(ctx, id, fields)
+}
+
+func (s *CachingStore) Delete(ctx context.Context, n *Note) error {
+	defer s.invalidate()
+	return s.store.Delete(ctx, n)
+}
+
+func (s *CachingStore) DeleteMany(ctx context.Context, notes []*Note) error {
+	defer s.invalidate()
+	return s.store.DeleteMany(ctx, notes)
+}
+
+func (s *CachingStore) ReplaceAll(ctx context.Context, notes []*Note) error {
+	defer s.invalidate()
+	return s.store.ReplaceAll(ctx, notes)
+}
+
+// invalidate drops the cached notes. A fetch already in flight still answers
+// the callers waiting on it but isn't cached.
+func (s *CachingStore) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.valid = false
+	s.notes = nil
+	s.gen++
+}
@@ -0,0 +1,155 @@
+package grocery
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingStore counts All calls to a MemoryStore, optionally holding each
+// one until release is closed or its ctx is done.
+type countingStore struct {
+	*MemoryStore
+	all     int32
+	release chan struct{}
+}
+
+func (s *countingStore) All(ctx context.Context) ([]*Note, error) {
+	atomic.AddInt32(&s.all, 1)
+	if s.release != nil {
+		select {
+		case <-s.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return s.MemoryStore.All(ctx)
+}
+
+func (s *countingStore) calls() int {
+	return int(atomic.LoadInt32(&s.all))
+}
+
+func TestCachingStoreServesFromCache(t *testing.T) {
+	ctx := context.Background()
+	store := &countingStore{MemoryStore: NewMemoryStore(&Note{Text: "apples"})}
+	cache := NewCachingStore(store, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		notes, err := cache.All(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(notes) != 1 || notes[0].Text != "apples" {
+			t.Fatalf("expected apples but was %+v", notes)
+		}
+	}
+	if store.calls() != 1 {
+		t.Fatal("expected one fetch from the wrapped store but was", store.calls())
+	}
+}
+
+func TestCachingStoreExpires(t *testing.T) {
+	ctx := context.Background()
+	store := &countingStore{MemoryStore: NewMemoryStore(&Note{Text: "apples"})}
+	cache := NewCachingStore(store, time.Minute)
+
+	clock := newFakeClock()
+	cache.Clock = clock
+	cache.All(ctx)
+	clock.Advance(59 * time.Second)
+	cache.All(ctx)
+	if store.calls() != 1 {
+		t.Fatal("expected the fresh cache to be served but was", store.calls())
+	}
+	clock.Advance(time.Second)
+	cache.All(ctx)
+
+	if store.calls() != 2 {
+		t.Fatal("expected the expired cache to refetch but was", store.calls())
+	}
+}
+
+func TestCachingStoreCreateBustsCache(t *testing.T) {
+	ctx := context.Background()
+	store := &countingStore{MemoryStore: NewMemoryStore(&Note{Text: "apples"})}
+	cache := NewCachingStore(store, time.Minute)
+
+	cache.All(ctx)
+	if err := cache.Create(ctx, &Note{Text: "milk"}); err != nil {
+		t.Fatal(err)
+	}
+	notes, err := cache.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 2 {
+		t.Fatalf("expected the new note after a create but was %+v", notes)
+	}
+	if store.calls() != 2 {
+		t.Fatal("expected create to force a refetch but was", store.calls())
+	}
+}
+
+func TestCachingStoreSharesInflightFetch(t *testing.T) {
+	ctx := context.Background()
+	store := &countingStore{MemoryStore: NewMemoryStore(&Note{Text: "apples"}), release: make(chan struct{})}
+	cache := NewCachingStore(store, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			notes, err := cache.All(ctx)
+			if err != nil || len(notes) != 1 {
+				t.Errorf("expected apples but was %+v, %v", notes, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(store.release)
+	wg.Wait()
+
+	if store.calls() != 1 {
+		t.Fatal("expected concurrent callers to share one fetch but was", store.calls())
+	}
+}
+
+func TestCachingStoreFetchOutlivesFirstCaller(t *testing.T) {
+	store := &countingStore{MemoryStore: NewMemoryStore(&Note{Text: "apples"}), release: make(chan struct{})}
+	cache := NewCachingStore(store, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := cache.All(ctx)
+		first <- err
+	}()
+	for store.calls() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	second := make(chan error)
+	go func() {
+		notes, err := cache.All(context.Background())
+		if err == nil && len(notes) != 1 {
+			t.Errorf("expected apples but was %+v", notes)
+		}
+		second <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	cancel()
+	if err := <-first; err != context.Canceled {
+		t.Fatal("expected the first caller to stop with its ctx but was", err)
+	}
+	close(store.release)
+	if err := <-second; err != nil {
+		t.Fatal("expected the fetch to go on for the second caller but was", err)
+	}
+	if store.calls() != 1 {
+		t.Fatal("expected the callers to share one fetch but was", store.calls())
+	}
+}
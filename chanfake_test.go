@@ -0,0 +1,176 @@
+package grocery
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// chanFake is the machinery behind a channel-driven fake such as FakeClient.
+// The code under test hands each call to the test over Calls and blocks until
+// the test, in an assertion, receives it and sends back a response on the
+// same channel. Either side failing to show up within the timeout fails the
+// test. A fake for another interface embeds a *chanFake, makes its calls
+// with roundTrip and writes its assertions with expect and reply.
+type chanFake struct {
+	t       testing.TB
+	Calls   chan Call
+	timeout time.Duration
+
+	mu        sync.Mutex
+	waiting   map[string]int
+	closeOnce sync.Once
+}
+
+func newChanFake(t testing.TB, timeout time.Duration) *chanFake {
+	return &chanFake{
+		t:       t,
+		Calls:   make(chan Call),
+		timeout: timeout,
+		waiting: map[string]int{},
+	}
+}
+
+// roundTrip hands call to the assertion side and returns its response.
+func (c *chanFake) roundTrip(ctx context.Context, method string, call Call) (Call, error) {
+	if err := c.send(ctx, method, call); err != nil {
+		return nil, err
+	}
+	return c.respond(method), nil
+}
+
+// send hands call to the assertion side, giving up if ctx is done first.
+// Once an assertion has the call its reply is always waited for, since the
+// reply would otherwise be left on Calls for the next call to receive.
+func (c *chanFake) send(ctx context.Context, method string, call Call) error {
+	select {
+	case c.Calls <- call:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(c.timeout):
+		c.t.Fatalf("no assertion consumed the %s call within %v", method, c.timeout)
+		return nil
+	}
+}
+
+// respond waits for the assertion side to answer a call to method.
+func (c *chanFake) respond(method string) Call {
+	select {
+	case resp := <-c.Calls:
+		return resp
+	case <-time.After(c.timeout):
+		c.t.Fatalf("no response to the %s call within %v", method, c.timeout)
+		return nil
+	}
+}
+
+// awaited reports whether an assertion is waiting for a call to method.
+func (c *chanFake) awaited(method string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.waiting[method] > 0
+}
+
+// expect waits for the code under test to make a call to method.
+func (c *chanFake) expect(method string) Call {
+	return c.expectWithin(method, c.timeout)
+}
+
+// expectWithin is expect, waiting up to d rather than the fake's timeout.
+func (c *chanFake) expectWithin(method string, d time.Duration) Call {
+	c.mu.Lock()
+	c.waiting[method]++
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.waiting[method]--
+		c.mu.Unlock()
+	}()
+
+	select {
+	case call, ok := <-c.Calls:
+		if !ok {
+			c.t.Fatalf("expected a %s call but the client was closed", method)
+		}
+		return call
+	case <-time.After(d):
+		c.t.Fatalf("expected a %s call but none arrived within %v", method, d)
+		return nil
+	}
+}
+
+// reply sends resp back to the code under test blocked in method.
+func (c *chanFake) reply(method string, resp Call) {
+	select {
+	case c.Calls <- resp:
+	case <-time.After(c.timeout):
+		c.t.Fatalf("the %s response was not consumed within %v", method, c.timeout)
+	}
+}
+
+// Close ends the calls, so AssertDone passes. Closing more than once does
+// nothing.
+func (c *chanFake) Close() error {
+	c.closeOnce.Do(func() { close(c.Calls) })
+	return nil
+}
+
+// AssertDone fails the test unless the client is closed with no more calls
+// made.
+func (c *chanFake) AssertDone() {
+	select {
+	case _, more := <-c.Calls:
+		if more {
+			c.t.Fatal("Did not expect more calls")
+		}
+	case <-time.After(c.timeout):
+		c.t.Fatalf("client was not closed within %v", c.timeout)
+	}
+}
+
+// chanRecorder is a MetricsRecorder built on chanFake, showing what a fake
+// for another interface takes.
+type chanRecorder struct{ *chanFake }
+
+type observeCall struct {
+	method string
+	err    error
+}
+
+// ObserveCall blocks until AssertObserve answers it; the response is
+// discarded since ObserveCall returns nothing.
+func (r chanRecorder) ObserveCall(method string, dur time.Duration, err error) {
+	r.roundTrip(context.Background(), "ObserveCall", observeCall{method, err})
+}
+
+func (r chanRecorder) AssertObserve(method string, err error) {
+	call, ok := r.expect("ObserveCall").(observeCall)
+	if !ok {
+		r.t.Fatal("expected an ObserveCall call")
+	}
+	if call.method != method || !errors.Is(call.err, err) {
+		r.t.Errorf("expected %s observed with %v but was %s with %v", method, err, call.method, call.err)
+	}
+	r.reply("ObserveCall", nil)
+}
+
+func TestChanFake(t *testing.T) {
+	rec := chanRecorder{newChanFake(t, defaultFakeTimeout)}
+	store := NewInstrumentedStore(NewMemoryStore(), rec)
+
+	go func() {
+		rec.AssertObserve("Create", nil)
+		rec.AssertObserve("Get", ErrItemNotFound)
+		rec.Close()
+	}()
+	if err := store.Create(context.Background(), &Note{Text: "apples"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(context.Background(), "milk"); !errors.Is(err, ErrItemNotFound) {
+		t.Fatal("expected ErrItemNotFound but was", err)
+	}
+	rec.AssertDone()
+}
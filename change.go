@@ -0,0 +1,202 @@
+package grocery
+
+import "sync"
+
+// ChangeOp is the kind of change a ChangeEvent reports.
+type ChangeOp int
+
+const (
+	ChangeAdd ChangeOp = iota
+	ChangeRemove
+	ChangeUpdate
+	// ChangeClear reports that every item was removed at once: by ClearAll
+	// on a store that is a Clearer, or by SetItems before the adds of the
+	// items it sets. Its event has no Note.
+	ChangeClear
+)
+
+func (op ChangeOp) String() string {
+	switch op {
+	case ChangeAdd:
+		return "add"
+	case ChangeRemove:
+		return "remove"
+	case ChangeUpdate:
+		return "update"
+	case ChangeClear:
+		return "clear"
+	}
+	return "unknown"
+}
+
+// ChangeEvent is a change made to a GroceryList. Note is a copy of the note
+// as it was stored.
+type ChangeEvent struct {
+	Op   ChangeOp
+	Note *Note
+}
+
+// OnChange registers fn to be called after every successful change to the
+// list. Observers are called in the order they were registered, from the
+// goroutine that made the change and after the list is unlocked, so they
+// may use the list themselves.
+func (g *GroceryList) OnChange(fn func(ChangeEvent)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.observers = append(g.observers, fn)
+}
+
+// Overflow decides what happens to the events a subscriber isn't ready for.
+type Overflow int
+
+const (
+	// OverflowDrop drops events while the subscriber's buffer is full.
+	OverflowDrop Overflow = iota
+	// OverflowBuffer queues them, without limit, until they are received.
+	OverflowBuffer
+)
+
+// Subscribe returns a channel that receives the events OnChange observers
+// get, and a function that unsubscribes and closes the channel. The channel
+// has room for buffer events; a subscriber that falls behind never blocks
+// the list, and is instead handled as overflow says. Calling unsubscribe
+// more than once does nothing.
+func (g *GroceryList) Subscribe(buffer int, overflow Overflow) (<-chan ChangeEvent, func()) {
+	s := &subscriber{
+		c:        make(chan ChangeEvent, buffer),
+		overflow: overflow,
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	if overflow == OverflowBuffer {
+		go s.pump()
+	}
+
+	g.mu.Lock()
+	g.subscribers = append(g.subscribers, s)
+	g.mu.Unlock()
+
+	var once sync.Once
+	return s.c, func() {
+		once.Do(func() {
+			g.mu.Lock()
+			for i, other := range g.subscribers {
+				if other == s {
+					g.subscribers = append(g.subscribers[:i:i], g.subscribers[i+1:]...)
+					break
+				}
+			}
+			g.mu.Unlock()
+			s.close()
+		})
+	}
+}
+
+// subscriber is a channel from Subscribe. Under OverflowDrop events are
+// sent straight to c; under OverflowBuffer they are queued for pump to send.
+type subscriber struct {
+	c        chan ChangeEvent
+	overflow Overflow
+	wake     chan struct{}
+	done     chan struct{}
+
+	mu     sync.Mutex
+	queue  []ChangeEvent
+	closed bool
+}
+
+func (s *subscriber) send(e ChangeEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	if s.overflow == OverflowDrop {
+		select {
+		case s.c <- e:
+		default:
+		}
+		return
+	}
+	s.queue = append(s.queue, e)
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// pump sends the queued events to c until the subscriber is closed, then
+// closes c.
+func (s *subscriber) pump() {
+	defer close(s.c)
+	for {
+		select {
+		case <-s.wake:
+		case <-s.done:
+			return
+		}
+		s.mu.Lock()
+		queue := s.queue
+		s.queue = nil
+		s.mu.Unlock()
+		for _, e := range queue {
+			select {
+			case s.c <- e:
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+func (s *subscriber) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.queue = nil
+	close(s.done)
+	if s.overflow == OverflowDrop {
+		close(s.c)
+	}
+}
+
+// changes collects the events of a call to send once it releases g.mu.
+type changes []ChangeEvent
+
+func (c *changes) add(op ChangeOp, n *Note) {
+	var note *Note
+	if n != nil {
+		note = cloneNote(n)
+	}
+	*c = append(*c, ChangeEvent{op, note})
+}
+
+// changed records a change to n, which was prev before an update, logs it
+// for Undo and drops the notes fetched by a sync. g.mu must be held.
+func (g *GroceryList) changed(events *changes, op ChangeOp, n, prev *Note) {
+	events.add(op, n)
+	g.logUndo(op, n, prev)
+	g.invalidate()
+}
+
+// notify sends events to the observers. Callers defer it before locking
+// g.mu so that it runs after the unlock.
+func (g *GroceryList) notify(events *changes) {
+	if len(*events) == 0 {
+		return
+	}
+	g.mu.RLock()
+	observers := g.observers
+	subscribers := g.subscribers
+	g.mu.RUnlock()
+
+	for _, e := range *events {
+		for _, fn := range observers {
+			fn(e)
+		}
+		for _, s := range subscribers {
+			s.send(e)
+		}
+	}
+}
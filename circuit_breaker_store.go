@@ -0,0 +1,230 @@
+package grocery
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// CircuitBreakerStore wraps an API and stops calling it after threshold
+// consecutive failures. While open every call fails fast with
+// ErrCircuitOpen. After cooldown a single probe call is let through: if it
+// succeeds the breaker closes again, otherwise it reopens for another
+// cooldown. Cancelled calls count as neither failure nor success, so a
+// cancelled probe leaves room for another. The cooldown is measured on Clock,
+// the wall clock when nil.
+//
+// IsFailure decides which errors count as failures. When nil, errors that
+// show the store is healthy but refused the call don't count: 4xx responses
+// such as ErrItemNotFound and ErrConflict, invalid items and a *BatchError.
+// Transport errors, 5xx responses and any other error do.
+type CircuitBreakerStore struct {
+	Clock     Clock
+	IsFailure func(error) bool
+
+	store     API
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	opened   time.Time
+}
+
+func NewCircuitBreakerStore(store API, threshold int, cooldown time.Duration) *CircuitBreakerStore {
+	return &CircuitBreakerStore{store: store, threshold: threshold, cooldown: cooldown}
+}
+
+// call runs fn unless the breaker is open and records how it went.
+func (s *CircuitBreakerStore) call(fn func() error) error {
+	if err := s.allow(); err != nil {
+		return err
+	}
+	err := fn()
+	s.record(err)
+	return err
+}
+
+func (s *CircuitBreakerStore) allow() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch s.state {
+	case circuitOpen:
+		if clockOr(s.Clock).Now().Sub(s.opened) < s.cooldown {
+			return ErrCircuitOpen
+		}
+		s.state = circuitHalfOpen
+	case circuitHalfOpen:
+		// A probe is already in flight.
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+func (s *CircuitBreakerStore) record(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cancelled(err) {
+		// No result: a probe that never finished leaves the breaker open
+		// with its cooldown over, so the next call probes again.
+		if s.state == circuitHalfOpen {
+			s.state = circuitOpen
+		}
+		return
+	}
+	if !s.failure(err) {
+		s.state = circuitClosed
+		s.failures = 0
+		return
+	}
+	s.failures++
+	if s.state == circuitHalfOpen || s.failures >= s.threshold {
+		s.state = circuitOpen
+		s.opened = clockOr(s.Clock).Now()
+	}
+}
+
+func (s *CircuitBreakerStore) failure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if s.IsFailure != nil {
+		return s.IsFailure(err)
+	}
+	return !refused(err)
+}
+
+// refusals are the errors of a store that is up but won't take the call.
+var refusals = []error{
+	ErrItemNotFound, ErrConflict, ErrEmptyItem, ErrItemTooLong,
+	ErrDuplicateItem, ErrUnitMismatch, ErrUnknownSortField,
+	ErrNegativeQuantity, ErrUnitWithoutQuantity, ErrListFull, ErrNoTenant,
+	ErrResponseTooLarge, ErrRedirectRefused,
+}
+
+// refused reports whether err shows the store answered but refused the
+// call, rather than failed.
+func refused(err error) bool {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return he.StatusCode < 500
+	}
+	var be *BatchError
+	if errors.As(err, &be) {
+		return true
+	}
+	for _, r := range refusals {
+		if errors.Is(err, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func cancelled(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func (s *CircuitBreakerStore) Create(ctx context.Context, n *Note) error {
+	return s.call(func() error {
+		return s.store.Create(ctx, n)
+	})
+}
+
+func (s *CircuitBreakerStore) CreateMany(ctx context.Context, notes []*Note) error {
+	return s.call(func() error {
+		return s.store.CreateMany(ctx, notes)
+	})
+}
+
+func (s *CircuitBreakerStore) All(ctx context.Context) ([]*Note, error) {
+	var notes []*Note
+	err := s.call(func() error {
+		var err error
+		notes, err = s.store.All(ctx)
+		return err
+	})
+	return notes, err
+}
+
+func (s *CircuitBreakerStore) Get(ctx context.Context, text string) (*Note, error) {
+	var n *Note
+	err := s.call(func() error {
+		var err error
+		n, err = s.store.Get(ctx, text)
+		return err
+	})
+	return n, err
+}
+
+func (s *CircuitBreakerStore) GetMany(ctx context.Context, texts []string) (map[string]*Note, error) {
+	var notes map[string]*Note
+	err := s.call(func() error {
+		var err error
+		notes, err = s.store.GetMany(ctx, texts)
+		return err
+	})
+	return notes, err
+}
+
+func (s *CircuitBreakerStore) Count(ctx context.Context) (int, error) {
+	var n int
+	err := s.call(func() error {
+		var err error
+		n, err = countNotes(ctx, s.store)
+		return err
+	})
+	return n, err
+}
+
+func (s *CircuitBreakerStore) Update(ctx context.Context, n *Note) error {
+	return s.call(func() error {
+		return s.store.Update(ctx, n)
+	})
+}
+
+func (s *CircuitBreakerStore) UpdateFields(ctx context.Context, id string, fields map[string]interface{}) error {
+	return s.call(func() error {
+		return s.store.UpdateFields(ctx, id, fields)
+	})
+}
+
+func (s *CircuitBreakerStore) Delete(ctx context.Context, n *Note) error {
+	return s.call(func() error {
+		return s.store.Delete(ctx, n)
+	})
+}
+
+func (s *CircuitBreakerStore) DeleteMany(ctx context.Context, notes []*Note) error {
+	return s.call(func() error {
+		return s.store.DeleteMany(ctx, notes)
+	})
+}
+
+func (s *CircuitBreakerStore) ReplaceAll(ctx context.Context, notes []*Note) error {
+	return s.call(func() error {
+		return s.store.ReplaceAll(ctx, notes)
+	})
+}
+
+func (s *CircuitBreakerStore) Ping(ctx context.Context) error {
+	return s.call(func() error {
+		return s.store.Ping(ctx)
+	})
+}
+
+func (s *CircuitBreakerStore) Close() error {
+	return closeStore(s.store)
+}
@@ -0,0 +1,133 @@
+package grocery
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerStore(t *testing.T) {
+	ctx := context.Background()
+	store := &flakyStore{MemoryStore: NewMemoryStore(&Note{Text: "apples"}), fails: 4}
+	breaker := NewCircuitBreakerStore(store, 3, time.Minute)
+	clock := newFakeClock()
+	breaker.Clock = clock
+
+	for i := 0; i < 3; i++ {
+		if _, err := breaker.All(ctx); !errors.Is(err, errFlaky) {
+			t.Fatal("expected flaky but was", err)
+		}
+	}
+	if _, err := breaker.All(ctx); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("expected ErrCircuitOpen but was", err)
+	}
+	if store.attempts != 3 {
+		t.Fatal("expected the open breaker not to call the store but it made", store.attempts, "attempts")
+	}
+
+	// The probe after the cooldown fails, so the breaker opens again.
+	clock.Advance(time.Minute)
+	if _, err := breaker.All(ctx); !errors.Is(err, errFlaky) {
+		t.Fatal("expected the probe to fail with flaky but was", err)
+	}
+	if _, err := breaker.All(ctx); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("expected ErrCircuitOpen but was", err)
+	}
+
+	clock.Advance(time.Minute)
+	notes, err := breaker.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 {
+		t.Fatal("expected apples but was", formatNotes(notes))
+	}
+	if _, err := breaker.All(ctx); err != nil {
+		t.Fatal("expected the breaker to be closed but was", err)
+	}
+	if store.attempts != 6 {
+		t.Fatal("expected 6 attempts but was", store.attempts)
+	}
+}
+
+func TestCircuitBreakerStoreIgnoresNotFound(t *testing.T) {
+	ctx := context.Background()
+	breaker := NewCircuitBreakerStore(&MemoryStore{}, 1, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if err := breaker.Delete(ctx, &Note{ID: "1"}); !errors.Is(err, ErrItemNotFound) {
+			t.Fatal("expected ErrItemNotFound but was", err)
+		}
+	}
+}
+
+func TestCircuitBreakerStoreCancelledProbe(t *testing.T) {
+	ctx := context.Background()
+	store := &flakyStore{MemoryStore: NewMemoryStore(&Note{Text: "apples"}), fails: 3}
+	breaker := NewCircuitBreakerStore(store, 2, time.Minute)
+	clock := newFakeClock()
+	breaker.Clock = clock
+
+	for i := 0; i < 2; i++ {
+		if _, err := breaker.All(ctx); !errors.Is(err, errFlaky) {
+			t.Fatal("expected flaky but was", err)
+		}
+	}
+	clock.Advance(time.Minute)
+	if err := breaker.call(func() error { return context.Canceled }); err != context.Canceled {
+		t.Fatal("expected the probe to be cancelled but was", err)
+	}
+
+	// The cancelled probe proved nothing, so another is let through, and its
+	// failure reopens the breaker.
+	if _, err := breaker.All(ctx); !errors.Is(err, errFlaky) {
+		t.Fatal("expected another probe to fail with flaky but was", err)
+	}
+	if _, err := breaker.All(ctx); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("expected ErrCircuitOpen but was", err)
+	}
+}
+
+// erringStore fails every Update with err.
+type erringStore struct {
+	*MemoryStore
+	err error
+}
+
+func (s *erringStore) Update(ctx context.Context, n *Note) error {
+	return s.err
+}
+
+func TestCircuitBreakerStoreIgnoresRefusals(t *testing.T) {
+	ctx := context.Background()
+	for _, err := range []error{
+		ErrConflict,
+		&HTTPError{StatusCode: http.StatusBadRequest},
+		&HTTPError{StatusCode: http.StatusPreconditionFailed},
+		ErrItemTooLong,
+	} {
+		breaker := NewCircuitBreakerStore(&erringStore{MemoryStore: &MemoryStore{}, err: err}, 2, time.Minute)
+		for i := 0; i < 5; i++ {
+			if got := breaker.Update(ctx, &Note{ID: "1"}); got != err {
+				t.Fatalf("expected %v to leave the breaker closed but was %v", err, got)
+			}
+		}
+	}
+
+	breaker := NewCircuitBreakerStore(&erringStore{MemoryStore: &MemoryStore{}, err: &HTTPError{StatusCode: http.StatusServiceUnavailable}}, 2, time.Minute)
+	for i := 0; i < 2; i++ {
+		breaker.Update(ctx, &Note{ID: "1"})
+	}
+	if err := breaker.Update(ctx, &Note{ID: "1"}); !errors.Is(err, ErrCircuitOpen) {
+		t.Error("expected 503s to open the breaker but was", err)
+	}
+
+	breaker = NewCircuitBreakerStore(&erringStore{MemoryStore: &MemoryStore{}, err: ErrConflict}, 1, time.Minute)
+	breaker.IsFailure = func(err error) bool { return true }
+	breaker.Update(ctx, &Note{ID: "1"})
+	if err := breaker.Update(ctx, &Note{ID: "1"}); !errors.Is(err, ErrCircuitOpen) {
+		t.Error("expected IsFailure to count conflicts but was", err)
+	}
+}
@@ -0,0 +1,24 @@
+package grocery
+
+import "time"
+
+// Clock tells the time and waits. GroceryList, HTTPClient and the stores
+// that wrap another read time from a Clock so that tests can drive it; a
+// nil Clock is the wall clock.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type wallClock struct{}
+
+func (wallClock) Now() time.Time                         { return time.Now() }
+func (wallClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// clockOr returns c, or the wall clock when c is nil.
+func clockOr(c Clock) Clock {
+	if c == nil {
+		return wallClock{}
+	}
+	return c
+}
@@ -0,0 +1,109 @@
+package grocery
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock that only moves when Advance is called, firing the
+// After channels whose time has come.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{c.now.Add(d), ch})
+	return ch
+}
+
+// Advance moves the clock forward by d.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	waiting := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			waiting = append(waiting, w)
+			continue
+		}
+		w.c <- c.now
+	}
+	c.waiters = waiting
+}
+
+// waitForWaiters blocks until n After channels are waiting to fire.
+func (c *fakeClock) waitForWaiters(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(defaultFakeTimeout)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		waiting := len(c.waiters)
+		c.mu.Unlock()
+		if waiting >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d waiters within %v", n, defaultFakeTimeout)
+}
+
+func TestRetryPolicyWaitsOnClock(t *testing.T) {
+	clock := newFakeClock()
+	p := RetryPolicy{MaxRetries: 2, BaseDelay: time.Hour, Retryable: func(error) bool { return true }}
+	flaky := errors.New("flaky")
+
+	attempts := make(chan int, 3)
+	done := make(chan error, 1)
+	go func() {
+		n := 0
+		done <- p.do(context.Background(), clock, func() error {
+			n++
+			attempts <- n
+			return flaky
+		})
+	}()
+
+	<-attempts
+	clock.waitForWaiters(t, 1)
+	clock.Advance(time.Hour)
+	<-attempts
+	clock.waitForWaiters(t, 1)
+	select {
+	case <-attempts:
+		t.Fatal("expected the second retry to wait two hours")
+	default:
+	}
+	clock.Advance(2 * time.Hour)
+	<-attempts
+	if err := <-done; !errors.Is(err, flaky) {
+		t.Fatal("expected flaky once retries ran out but was", err)
+	}
+}
@@ -0,0 +1,85 @@
+package grocery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+)
+
+// Codec is the wire format an HTTPClient speaks. ContentType is sent as the
+// Content-Type of request bodies and the Accept of every request.
+type Codec interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec is the Codec HTTPClient uses by default, encoding/json.
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string {
+	return "application/json"
+}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// FormContentType is the HTTPClient ContentType that sends Create bodies as
+// form values, for backends that only take forms.
+const FormContentType = "application/x-www-form-urlencoded"
+
+// formEncode encodes v as form values named after its JSON fields. Objects,
+// such as Note.Meta, give a value per key named field[key], and null
+// fields are left out.
+func formEncode(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var fields interface{}
+	if err := dec.Decode(&fields); err != nil {
+		return nil, err
+	}
+	values := url.Values{}
+	addForm(values, "", fields)
+	return []byte(values.Encode()), nil
+}
+
+func addForm(values url.Values, name string, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, field := range v {
+			if name != "" {
+				k = name + "[" + k + "]"
+			}
+			addForm(values, k, field)
+		}
+	case []interface{}:
+		for _, elem := range v {
+			addForm(values, name, elem)
+		}
+	case nil:
+	default:
+		values.Add(name, fmt.Sprint(v))
+	}
+}
+
+// sameMediaType reports whether the Content-Type headers a and b name the
+// same media type, whatever their parameters.
+func sameMediaType(a, b string) bool {
+	ma, _, err := mime.ParseMediaType(a)
+	if err != nil {
+		return false
+	}
+	mb, _, err := mime.ParseMediaType(b)
+	return err == nil && ma == mb
+}
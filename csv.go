@@ -0,0 +1,311 @@
+package grocery
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var csvHeader = []string{"id", "text", "quantity", "unit", "category", "purchased", "archived", "meta", "created_at", "updated_at"}
+
+// ExportCSV writes every note to w as CSV, with a header row. Meta is a JSON
+// object, and timestamps are RFC 3339; both are left empty when there are
+// none.
+func (g *GroceryList) ExportCSV(ctx context.Context, w io.Writer) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	notes, err := g.Store.All(ctx)
+	if err != nil {
+		return wrap("exporting items", err)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, n := range notes {
+		cw.Write(csvRecord(n))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvFlushRows is how many rows ExportCSVStream writes between flushes.
+const csvFlushRows = 100
+
+// ExportCSVStream is ExportCSV for lists too big to fetch at once. From a
+// store that is a Streamer it writes each note as it arrives, flushing every
+// csvFlushRows rows, without holding the list's lock; other stores are
+// exported with ExportCSV. It stops with ctx's error when ctx is done and
+// returns the stream's error if it fails, having written the rows before.
+func (g *GroceryList) ExportCSVStream(ctx context.Context, w io.Writer) error {
+	streamer, ok := g.Store.(Streamer)
+	if !ok {
+		return g.ExportCSV(ctx, w)
+	}
+	notes, errc := streamer.AllStream(ctx)
+
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	rows := 0
+	for n := range notes {
+		if err := ctx.Err(); err != nil {
+			cw.Flush()
+			return err
+		}
+		cw.Write(csvRecord(n))
+		if rows++; rows%csvFlushRows == 0 {
+			cw.Flush()
+			if err := cw.Error(); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := <-errc; err != nil {
+		return wrap("exporting items", err)
+	}
+	return cw.Error()
+}
+
+func csvRecord(n *Note) []string {
+	return []string{
+		n.ID,
+		n.Text,
+		strconv.Itoa(n.Quantity),
+		n.Unit,
+		n.Category,
+		strconv.FormatBool(n.Purchased),
+		strconv.FormatBool(n.Archived),
+		csvMeta(n.Meta),
+		csvTime(n.CreatedAt),
+		csvTime(n.UpdatedAt),
+	}
+}
+
+func csvMeta(meta map[string]string) string {
+	if len(meta) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(meta)
+	return string(b)
+}
+
+func csvTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// ImportCSV adds the items in the CSV read from r in one CreateMany call and
+// returns how many were imported and how many were skipped as duplicates,
+// unless duplicates are allowed. Duplicates are skipped under
+// AddIncrementQuantity too. With IdempotentImports, items are duplicates
+// only when their category matches too, and are skipped even when
+// duplicates are allowed, so importing a file again imports nothing. Rows
+// with every field empty are ignored. When the store fails to create some
+// of the items, imported counts those it did create.
+//
+// A first row naming a "text" column is a header, and the columns are then
+// read by name as ExportCSV writes them; ids and timestamps are ignored.
+// Without a header the columns are text, quantity, category, purchased and
+// unit, all but text optional. Quantity defaults to 1.
+//
+// Nothing is imported if any row is malformed, and the error gives its line.
+func (g *GroceryList) ImportCSV(ctx context.Context, r io.Reader) (imported, skipped int, err error) {
+	notes, err := g.parseCSV(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	return g.importNotes(ctx, notes)
+}
+
+// importNotes creates notes in one CreateMany call, skipping duplicates as
+// ImportCSV and ImportJSON describe.
+func (g *GroceryList) importNotes(ctx context.Context, notes []*Note) (imported, skipped int, err error) {
+	var events changes
+	defer g.notify(&events)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	added, dups, err := g.splitImport(ctx, notes)
+	if err != nil {
+		return 0, 0, err
+	}
+	imported, err = g.createMany(ctx, "importing", added, &events)
+	return imported, len(dups), err
+}
+
+// PlanImportCSV parses the CSV read from r as ImportCSV does and returns the
+// items ImportCSV would import and those it would skip as duplicates,
+// without creating any. Malformed rows fail as they do in ImportCSV, giving
+// their line.
+func (g *GroceryList) PlanImportCSV(ctx context.Context, r io.Reader) (toAdd, skipped []string, err error) {
+	notes, err := g.parseCSV(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	added, dups, err := g.splitImport(ctx, notes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return texts(added), texts(dups), nil
+}
+
+func texts(notes []*Note) []string {
+	items := make([]string, len(notes))
+	for i, n := range notes {
+		items[i] = n.Text
+	}
+	return items
+}
+
+// splitImport splits notes into those to import and those to skip as
+// duplicates of each other or of the list. g.mu must be held.
+func (g *GroceryList) splitImport(ctx context.Context, notes []*Note) (added, skipped []*Note, err error) {
+	dedup := g.IdempotentImports || !g.allowDuplicates()
+	seen := map[string]bool{}
+	if dedup {
+		existing, err := g.Store.All(ctx)
+		if err != nil {
+			return nil, nil, wrap("importing items", err)
+		}
+		for _, n := range existing {
+			seen[g.importKey(n)] = true
+		}
+	}
+	added = []*Note{}
+	skipped = []*Note{}
+	for _, n := range notes {
+		key := g.importKey(n)
+		if seen[key] {
+			skipped = append(skipped, n)
+			continue
+		}
+		if dedup {
+			seen[key] = true
+		}
+		added = append(added, n)
+	}
+	return added, skipped, nil
+}
+
+// importKey returns what an imported note must share with a note on the
+// list to be skipped.
+func (g *GroceryList) importKey(n *Note) string {
+	key := g.key(n.Text)
+	if g.IdempotentImports {
+		key += "\x00" + n.Category
+	}
+	return key
+}
+
+func (g *GroceryList) parseCSV(r io.Reader) ([]*Note, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	columns := map[string]int{"text": 0, "quantity": 1, "category": 2, "purchased": 3, "unit": 4}
+	notes := []*Note{}
+	for row := 0; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return notes, nil
+		}
+		if err != nil {
+			var pe *csv.ParseError
+			if errors.As(err, &pe) {
+				return nil, fmt.Errorf("grocery: importing items: line %d: %w", pe.Line, pe.Err)
+			}
+			return nil, wrap("importing items", err)
+		}
+		line, _ := cr.FieldPos(0)
+
+		if row == 0 && header(record) {
+			columns = map[string]int{}
+			for i, name := range record {
+				columns[strings.ToLower(strings.TrimSpace(name))] = i
+			}
+			continue
+		}
+		if blank(record) {
+			continue
+		}
+
+		n, err := g.parseRecord(record, columns)
+		if err != nil {
+			return nil, fmt.Errorf("grocery: importing items: line %d: %w", line, err)
+		}
+		notes = append(notes, n)
+	}
+}
+
+func header(record []string) bool {
+	for _, field := range record {
+		if strings.EqualFold(strings.TrimSpace(field), "text") {
+			return true
+		}
+	}
+	return false
+}
+
+func blank(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *GroceryList) parseRecord(record []string, columns map[string]int) (*Note, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	text, err := g.validate(field("text"))
+	if err != nil {
+		return nil, err
+	}
+	n := &Note{Text: text, Quantity: 1, Unit: field("unit"), Category: field("category")}
+	if q := field("quantity"); q != "" {
+		if n.Quantity, err = strconv.Atoi(q); err != nil {
+			return nil, fmt.Errorf("quantity %q is not a number", q)
+		}
+	}
+	if p := field("purchased"); p != "" {
+		if n.Purchased, err = strconv.ParseBool(p); err != nil {
+			return nil, fmt.Errorf("purchased %q is not true or false", p)
+		}
+	}
+	if a := field("archived"); a != "" {
+		if n.Archived, err = strconv.ParseBool(a); err != nil {
+			return nil, fmt.Errorf("archived %q is not true or false", a)
+		}
+	}
+	if m := field("meta"); m != "" {
+		if err := json.Unmarshal([]byte(m), &n.Meta); err != nil {
+			return nil, fmt.Errorf("meta %q is not a JSON object of strings", m)
+		}
+	}
+	if err := n.Validate(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
@@ -0,0 +1,273 @@
+package grocery
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGroceryListExportCSV(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	go func() {
+		client.AssertAll([]*Note{
+			{ID: "1", Text: "apples, green", Quantity: 3, Unit: "lb", Category: "produce", Meta: map[string]string{"brand": "acme"}, CreatedAt: created, UpdatedAt: created.Add(time.Hour)},
+			{ID: "2", Text: `12" pizza`, Quantity: 1, Purchased: true, Archived: true},
+		}, nil)
+		client.Close()
+	}()
+	var b strings.Builder
+	if err := list.ExportCSV(context.Background(), &b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "id,text,quantity,unit,category,purchased,archived,meta,created_at,updated_at\n" +
+		"1,\"apples, green\",3,lb,produce,false,false,\"{\"\"brand\"\":\"\"acme\"\"}\",2024-03-01T10:00:00Z,2024-03-01T11:00:00Z\n" +
+		"2,\"12\"\" pizza\",1,,,true,true,,,\n"
+	if b.String() != want {
+		t.Fatalf("expected\n%s\nbut was\n%s", want, b.String())
+	}
+	client.AssertDone()
+}
+
+// streamStore is a Streamer that streams count notes, then err, stopping
+// early when ctx is done. When cancel is set it is called once the note
+// numbered cancelAfter has been taken.
+type streamStore struct {
+	*MemoryStore
+	count       int
+	err         error
+	cancelAfter int
+	cancel      func()
+}
+
+func (s *streamStore) AllStream(ctx context.Context) (<-chan *Note, <-chan error) {
+	notes := make(chan *Note)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		defer close(notes)
+		for i := 0; i < s.count; i++ {
+			select {
+			case notes <- &Note{ID: strconv.Itoa(i + 1), Text: "item " + strconv.Itoa(i+1), Quantity: 1}:
+			case <-ctx.Done():
+				errc <- ctx.Err()
+				return
+			}
+			if s.cancel != nil && i+1 == s.cancelAfter {
+				s.cancel()
+			}
+		}
+		errc <- s.err
+	}()
+	return notes, errc
+}
+
+func TestGroceryListExportCSVStream(t *testing.T) {
+	list := New()
+	list.Store = &streamStore{MemoryStore: NewMemoryStore(), count: 1000}
+
+	var b strings.Builder
+	if err := list.ExportCSVStream(context.Background(), &b); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 1001 {
+		t.Fatalf("expected a header and 1000 rows but was %d lines", len(lines))
+	}
+	if lines[1000] != "1000,item 1000,1,,,false,false,,," {
+		t.Error("expected the last row for item 1000 but was", lines[1000])
+	}
+}
+
+func TestGroceryListExportCSVStreamFails(t *testing.T) {
+	boom := errors.New("boom")
+	list := New()
+	list.Store = &streamStore{MemoryStore: NewMemoryStore(), count: 3, err: boom}
+
+	var b strings.Builder
+	if err := list.ExportCSVStream(context.Background(), &b); !errors.Is(err, boom) {
+		t.Fatal("expected boom but was", err)
+	}
+	if n := strings.Count(b.String(), "\n"); n != 4 {
+		t.Error("expected the rows before the failure to be written but was", n)
+	}
+}
+
+func TestGroceryListExportCSVStreamCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	list := New()
+	list.Store = &streamStore{MemoryStore: NewMemoryStore(), count: 1000, cancelAfter: 10, cancel: cancel}
+
+	var b strings.Builder
+	err := list.ExportCSVStream(ctx, &b)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal("expected context.Canceled but was", err)
+	}
+	if n := strings.Count(b.String(), "\n"); n > 12 {
+		t.Error("expected the export to stop after about 10 rows but was", n-1)
+	}
+}
+
+func TestGroceryListCSVRoundTrip(t *testing.T) {
+	from := New()
+	from.Store = NewMemoryStore(
+		&Note{Text: "apples", Quantity: 3, Unit: "lb", Category: "produce", Meta: map[string]string{"brand": "acme"}},
+		&Note{Text: "tape", Quantity: 1, Archived: true},
+	)
+	var b bytes.Buffer
+	if err := from.ExportCSV(context.Background(), &b); err != nil {
+		t.Fatal(err)
+	}
+
+	to := New()
+	to.Store = &MemoryStore{}
+	if _, _, err := to.ImportCSV(context.Background(), &b); err != nil {
+		t.Fatal(err)
+	}
+	notes, err := to.Store.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Note{
+		{Text: "apples", Quantity: 3, Unit: "lb", Category: "produce", Meta: map[string]string{"brand": "acme"}},
+		{Text: "tape", Quantity: 1, Archived: true},
+	}
+	if len(notes) != len(want) {
+		t.Fatalf("expected %d notes but was %s", len(want), formatNotes(notes))
+	}
+	for i, n := range notes {
+		n.ID = ""
+		if !n.Equal(want[i]) {
+			t.Errorf("expected %+v but was %+v", *want[i], *n)
+		}
+	}
+}
+
+func TestGroceryListImportCSV(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{{ID: "1", Text: "milk"}}, nil)
+		client.AssertCreateMany([]*Note{
+			{Text: "apples, green", Quantity: 3, Category: "produce"},
+			{Text: "bread", Quantity: 1, Purchased: true},
+		}, nil)
+		client.Close()
+	}()
+	in := "text,quantity,category,purchased\n" +
+		"\"apples, green\",3,produce,false\n" +
+		"\n" +
+		"milk,2,,\n" +
+		"bread,,,true\n" +
+		"\"Apples, green\",1\n"
+	list.DedupMode = DedupIgnoreCase
+	imported, skipped, err := list.ImportCSV(context.Background(), strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 2 || skipped != 2 {
+		t.Fatalf("expected 2 imported and 2 skipped but was %d and %d", imported, skipped)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListImportCSVWithoutHeader(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+	list.AllowDuplicates = true
+
+	go func() {
+		client.AssertCreateMany([]*Note{{Text: "apples", Quantity: 1}, {Text: "eggs", Quantity: 12, Category: "dairy"}}, nil)
+		client.Close()
+	}()
+	imported, _, err := list.ImportCSV(context.Background(), strings.NewReader("apples\neggs,12,dairy\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 2 {
+		t.Fatal("expected 2 imported but was", imported)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListImportCSVIdempotent(t *testing.T) {
+	store := NewMemoryStore()
+	list := New()
+	list.Store = store
+	list.AllowDuplicates = true
+	list.IdempotentImports = true
+
+	in := "text,quantity,category\n" +
+		"apples,3,produce\n" +
+		"apples,1,baking\n" +
+		"milk,1,dairy\n" +
+		"milk,2,dairy\n"
+	imported, skipped, err := list.ImportCSV(context.Background(), strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 3 || skipped != 1 {
+		t.Fatalf("expected 3 imported and 1 skipped but was %d and %d", imported, skipped)
+	}
+
+	imported, skipped, err = list.ImportCSV(context.Background(), strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 0 || skipped != 4 {
+		t.Fatalf("expected 0 imported and 4 skipped but was %d and %d", imported, skipped)
+	}
+	if n, _ := store.Count(context.Background()); n != 3 {
+		t.Fatal("expected 3 items but was", n)
+	}
+}
+
+func TestGroceryListImportCSVMalformed(t *testing.T) {
+	list := New()
+	list.Store = NewFakeClient(t)
+
+	_, _, err := list.ImportCSV(context.Background(), strings.NewReader("apples,1\nmilk,lots\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") || !strings.Contains(err.Error(), "lots") {
+		t.Fatal("expected an error about line 2 but was", err)
+	}
+
+	_, _, err = list.ImportCSV(context.Background(), strings.NewReader("apples\n ,1\n"))
+	if !errors.Is(err, ErrEmptyItem) || !strings.Contains(err.Error(), "line 2") {
+		t.Fatal("expected an empty item on line 2 but was", err)
+	}
+}
+
+func TestGroceryListPlanImportCSV(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{{ID: "1", Text: "milk", Quantity: 1}}, nil)
+		client.Close()
+	}()
+	toAdd, skipped, err := list.PlanImportCSV(context.Background(), strings.NewReader("apples,3\nmilk\neggs,12\napples\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(toAdd, ",") != "apples,eggs" || strings.Join(skipped, ",") != "milk,apples" {
+		t.Fatalf("expected apples and eggs added and milk and apples skipped but was %q and %q", toAdd, skipped)
+	}
+	client.AssertDone()
+
+	_, _, err = list.PlanImportCSV(context.Background(), strings.NewReader("apples\nmilk,lots\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatal("expected an error on line 2 but was", err)
+	}
+}
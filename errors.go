@@ -0,0 +1,77 @@
+package grocery
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrItemNotFound        = errors.New("grocery: item not found")
+	ErrEmptyItem           = errors.New("grocery: item is empty")
+	ErrItemTooLong         = errors.New("grocery: item is too long")
+	ErrDuplicateItem       = errors.New("grocery: item is already on the list")
+	ErrCircuitOpen         = errors.New("grocery: circuit breaker is open")
+	ErrNothingToUndo       = errors.New("grocery: nothing to undo")
+	ErrUnitMismatch        = errors.New("grocery: item is already on the list in another unit")
+	ErrRequestTimeout      = errors.New("grocery: request timed out")
+	ErrUnknownSortField    = errors.New("grocery: unknown sort field")
+	ErrConflict            = errors.New("grocery: item was changed by someone else")
+	ErrNegativeQuantity    = errors.New("grocery: quantity is negative")
+	ErrUnitWithoutQuantity = errors.New("grocery: unit is given without a quantity")
+	ErrListFull            = errors.New("grocery: list is full")
+	ErrResponseTooLarge    = errors.New("grocery: response is too large")
+	ErrRedirectRefused     = errors.New("grocery: redirect refused")
+	ErrNoTenant            = errors.New("grocery: no tenant in context")
+	ErrSessionDone         = errors.New("grocery: session was already committed or rolled back")
+)
+
+// UnitMismatchError is returned by MergeFrom for the Items it left alone
+// because the two lists have them in different units. It matches
+// ErrUnitMismatch under errors.Is.
+type UnitMismatchError struct {
+	Items []string
+}
+
+func (e *UnitMismatchError) Error() string {
+	return fmt.Sprintf("grocery: merging lists: %d items are in another unit: %s", len(e.Items), strings.Join(e.Items, ", "))
+}
+
+func (e *UnitMismatchError) Is(target error) bool {
+	return target == ErrUnitMismatch
+}
+
+// HTTPError is returned by HTTPClient when the backend responds with a
+// non-2xx status. Status is the full status line, such as "404 Not Found",
+// and URL the request URL. RetryAfter is the wait asked for by the
+// Retry-After header of a 429 or 503, or zero without one. A 404 matches
+// ErrItemNotFound under errors.Is, and a 412 matches ErrConflict.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+	URL        string
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	status := e.Status
+	if status == "" {
+		status = strconv.Itoa(e.StatusCode)
+	}
+	return fmt.Sprintf("grocery: %s: unexpected status %s: %s", e.URL, status, bytes.TrimSpace(e.Body))
+}
+
+func (e *HTTPError) Is(target error) bool {
+	switch target {
+	case ErrItemNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusPreconditionFailed
+	}
+	return false
+}
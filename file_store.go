@@ -0,0 +1,208 @@
+package grocery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// FileStore is an API that persists notes as a JSON array in the file at
+// Path. A missing file is an empty list. Every change rewrites the whole file
+// by writing a temporary file alongside it and renaming it into place, so the
+// file is never left half written. Created notes get their IDs from IDGen
+// or Sequential as in a MemoryStore, and the IDs are saved with the notes.
+type FileStore struct {
+	Path       string
+	IDGen      func() string
+	Sequential bool
+
+	mu sync.Mutex
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+func (s *FileStore) Create(ctx context.Context, n *Note) error {
+	return s.update(func(m *MemoryStore) error {
+		return m.Create(ctx, n)
+	})
+}
+
+func (s *FileStore) CreateMany(ctx context.Context, notes []*Note) error {
+	return s.update(func(m *MemoryStore) error {
+		return m.CreateMany(ctx, notes)
+	})
+}
+
+func (s *FileStore) ReplaceAll(ctx context.Context, notes []*Note) error {
+	return s.update(func(m *MemoryStore) error {
+		return m.ReplaceAll(ctx, notes)
+	})
+}
+
+func (s *FileStore) All(ctx context.Context) ([]*Note, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return m.All(ctx)
+}
+
+func (s *FileStore) Get(ctx context.Context, text string) (*Note, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return m.Get(ctx, text)
+}
+
+func (s *FileStore) GetMany(ctx context.Context, texts []string) (map[string]*Note, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return m.GetMany(ctx, texts)
+}
+
+func (s *FileStore) Count(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, err := s.load()
+	if err != nil {
+		return 0, err
+	}
+	return m.Count(ctx)
+}
+
+// Ping checks that the file can be read.
+func (s *FileStore) Ping(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.load()
+	return err
+}
+
+func (s *FileStore) Update(ctx context.Context, n *Note) error {
+	return s.update(func(m *MemoryStore) error {
+		return m.Update(ctx, n)
+	})
+}
+
+func (s *FileStore) UpdateFields(ctx context.Context, id string, fields map[string]interface{}) error {
+	return s.update(func(m *MemoryStore) error {
+		return m.UpdateFields(ctx, id, fields)
+	})
+}
+
+func (s *FileStore) Delete(ctx context.Context, n *Note) error {
+	return s.update(func(m *MemoryStore) error {
+		return m.Delete(ctx, n)
+	})
+}
+
+// DeleteMany saves the notes that could be deleted even when others
+// couldn't.
+func (s *FileStore) DeleteMany(ctx context.Context, notes []*Note) error {
+	var partial error
+	err := s.update(func(m *MemoryStore) error {
+		err := m.DeleteMany(ctx, notes)
+		var be *BatchError
+		if errors.As(err, &be) {
+			partial = err
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return partial
+}
+
+func (s *FileStore) DeleteAll(ctx context.Context) error {
+	return s.update(func(m *MemoryStore) error {
+		return m.DeleteAll(ctx)
+	})
+}
+
+// update loads the file, applies fn to its notes and writes them back unless
+// fn fails.
+func (s *FileStore) update(fn func(*MemoryStore) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, err := s.load()
+	if err != nil {
+		return err
+	}
+	if err := fn(m); err != nil {
+		return err
+	}
+	return s.save(m.notes)
+}
+
+func (s *FileStore) load() (*MemoryStore, error) {
+	m := &MemoryStore{IDGen: s.IDGen, Sequential: s.Sequential}
+	b, err := os.ReadFile(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &m.notes); err != nil {
+		return nil, err
+	}
+	for _, n := range m.notes {
+		if id, err := strconv.Atoi(n.ID); err == nil && id > m.lastID {
+			m.lastID = id
+		}
+	}
+	return m, nil
+}
+
+func (s *FileStore) save(notes []*Note) error {
+	if notes == nil {
+		notes = []*Note{}
+	}
+	b, err := json.MarshalIndent(notes, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
@@ -0,0 +1,107 @@
+package grocery
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileStoreMissingFile(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "list.json"))
+
+	notes, err := store.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 0 {
+		t.Fatalf("expected no notes but was %+v", notes)
+	}
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "list.json")
+
+	list := New()
+	list.Store = &FileStore{Path: path, Sequential: true}
+	for _, item := range []string{"apples", "milk", "bread"} {
+		if err := list.AddItem(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := list.MarkPurchased(ctx, "milk"); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.RemoveItem(ctx, "apples"); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := &FileStore{Path: path, Sequential: true}
+	notes, err := reopened.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 2 {
+		t.Fatalf("expected two notes but was %+v", notes)
+	}
+	if !notes[0].Equal(&Note{ID: "2", Text: "milk", Quantity: 1, Purchased: true}) {
+		t.Errorf("expected purchased milk but was %+v", notes[0])
+	}
+	if !notes[1].Equal(&Note{ID: "3", Text: "bread", Quantity: 1}) {
+		t.Errorf("expected bread but was %+v", notes[1])
+	}
+
+	n := &Note{Text: "eggs"}
+	if err := reopened.Create(ctx, n); err != nil {
+		t.Fatal(err)
+	}
+	if n.ID != "4" {
+		t.Error("expected IDs to carry on from the file but was", n.ID)
+	}
+}
+
+func TestFileStoreIDGen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "list.json")
+
+	store := NewFileStore(path)
+	store.IDGen = sequence("a", "b")
+	n := &Note{Text: "apples"}
+	if err := store.Create(ctx, n); err != nil {
+		t.Fatal(err)
+	}
+	if n.ID != "a" {
+		t.Fatal("expected the generated ID a but was", n.ID)
+	}
+
+	reopened := NewFileStore(path)
+	reopened.IDGen = sequence("a", "b")
+	if err := reopened.Create(ctx, &Note{Text: "milk"}); err != nil {
+		t.Fatal(err)
+	}
+	notes, err := reopened.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 2 || notes[0].ID != "a" || notes[1].ID != "b" {
+		t.Fatalf("expected apples under a and milk under b but was %+v", notes)
+	}
+}
+
+func TestFileStoreLeavesNoTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(filepath.Join(dir, "list.json"))
+	store.Create(context.Background(), &Note{Text: "apples"})
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp") {
+			t.Error("expected no temp files but found", e.Name())
+		}
+	}
+}
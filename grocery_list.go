@@ -1,19 +1,1490 @@
 package grocery
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// GroceryList keeps its items in Store, which New points at an HTTPClient.
+// Store is the seam for injecting a fake API in tests.
+//
+// AddMode decides what adding an item that is already on the list does: by
+// default it is skipped, which costs an extra All call per add. DedupMode
+// decides when two items are the same; by default only identical text is.
+// AllowDuplicates predates AddMode and is the same as AddAllowDuplicates.
+//
+// Added items are normalized with Normalizer, NormalizeSpace by default,
+// and must not then be empty or, when MaxLength is set, longer than
+// MaxLength characters. Items are stored in their normalized form, and
+// Contains, Search and duplicate checks normalize the items they compare.
+//
+// When MaxItems is set, adding an item, or with AddItems several, that
+// would take the list past MaxItems items fails with ErrListFull.
+//
+// MergeMode decides what MergeFrom does with an item that is on both lists:
+// by default it is skipped.
+//
+// IdempotentImports makes ImportCSV and ImportJSON skip whatever is already
+// on the list, matching text and category; see ImportCSV.
+//
+// FailOpen makes Items, AppendItems, ItemsSorted and ItemsBy degrade when
+// the store fails: rather than no items, they return the items of the last
+// successful fetch for the same tenant from ContextWithTenant, from the
+// store or a sync, along with the error, so a UI can keep showing them while
+// it reports the failure. Until a fetch has succeeded they return no items,
+// as they do by default. The remembered items may be out of date, since
+// changes made since aren't in them.
+//
+// Up to UndoDepth of the latest changes are kept for Undo. Zero keeps none.
+//
+// Clock, the wall clock when nil, gives the time Touch sets and paces
+// StartSync.
+//
+// SyncError, when set, is called with the error of every failed fetch of the
+// sync started by StartSync, which otherwise goes on serving the notes of
+// the last fetch that succeeded.
+//
+// A GroceryList is safe for concurrent use, provided Store is too.
 type GroceryList struct {
-	Store API
+	Store             API
+	AddMode           AddMode
+	MergeMode         MergeMode
+	AllowDuplicates   bool
+	DedupMode         DedupMode
+	MaxLength         int
+	MaxItems          int
+	Normalizer        func(string) string
+	Clock             Clock
+	UndoDepth         int
+	IdempotentImports bool
+	FailOpen          bool
+	SyncError         func(error)
+
+	mu          sync.RWMutex
+	observers   []func(ChangeEvent)
+	subscribers []*subscriber
+	undo        []undoEntry
+	sync        syncState
+	lastGood    lastGood
+}
+
+type AddMode int
+
+const (
+	AddSkipDuplicates AddMode = iota
+	// AddIncrementQuantity adds the quantity of an item that is already on
+	// the list to the existing note, with Update. Adding it in a different
+	// unit fails with ErrUnitMismatch.
+	AddIncrementQuantity
+	AddAllowDuplicates
+)
+
+type MergeMode int
+
+const (
+	MergeSkipDuplicates MergeMode = iota
+	// MergeSumQuantities adds the quantity of the other list's item to the
+	// note already on the list, with Update.
+	MergeSumQuantities
+	// MergeMaxQuantity keeps the larger of the two quantities.
+	MergeMaxQuantity
+)
+
+// merge returns the quantity of an item on both lists with quantities
+// ours and theirs.
+func (m MergeMode) merge(ours, theirs int) int {
+	switch m {
+	case MergeSumQuantities:
+		return ours + theirs
+	case MergeMaxQuantity:
+		return max(ours, theirs)
+	}
+	return ours
+}
+
+func (g *GroceryList) allowDuplicates() bool {
+	return g.AllowDuplicates || g.AddMode == AddAllowDuplicates
+}
+
+type DedupMode int
+
+const (
+	DedupExact DedupMode = iota
+	DedupIgnoreCase
+	DedupIgnoreCaseAndSpace
+)
+
+// key returns the form of item that is equal for all items m considers the
+// same.
+func (m DedupMode) key(item string) string {
+	switch m {
+	case DedupIgnoreCase:
+		return strings.ToLower(item)
+	case DedupIgnoreCaseAndSpace:
+		return strings.ToLower(strings.TrimSpace(item))
+	}
+	return item
+}
+
+// same reports whether a and b are the same item under g.DedupMode.
+func (g *GroceryList) same(a, b string) bool {
+	return g.key(a) == g.key(b)
+}
+
+const Uncategorized = "uncategorized"
+
+type Item struct {
+	Text     string
+	Quantity int
+	Unit     string
+}
+
+// Summary aggregates the whole list. Quantities totals the quantities per
+// unit, with plain counts under "", since pounds and ounces don't add up.
+// ByCategory counts items per category, with items that have none under
+// Uncategorized.
+type Summary struct {
+	Total      int
+	Purchased  int
+	Pending    int
+	Quantities map[string]int
+	ByCategory map[string]int
 }
 
 func New() *GroceryList {
-	return &GroceryList{&HTTPClient{}}
+	return &GroceryList{Store: &HTTPClient{}}
+}
+
+func (g *GroceryList) AddItem(ctx context.Context, item string) error {
+	return g.AddItemWithQuantity(ctx, item, 1)
+}
+
+func (g *GroceryList) AddItemWithQuantity(ctx context.Context, item string, qty int) error {
+	_, err := g.add(ctx, &Note{Text: item, Quantity: qty})
+	return err
+}
+
+// AddItemWithUnit adds qty of unit of item, such as 2 lb of apples.
+func (g *GroceryList) AddItemWithUnit(ctx context.Context, item string, qty int, unit string) error {
+	_, err := g.add(ctx, &Note{Text: item, Quantity: qty, Unit: unit})
+	return err
+}
+
+func (g *GroceryList) AddItemInCategory(ctx context.Context, item, category string) error {
+	_, err := g.add(ctx, &Note{Text: item, Quantity: 1, Category: category})
+	return err
+}
+
+// AddItemReturning is AddItem that returns the note for item as the store
+// has it, with the ID and whatever else the store filled in on Create, for
+// later calls that need them. An item already on the list returns its
+// existing note, updated under AddIncrementQuantity.
+func (g *GroceryList) AddItemReturning(ctx context.Context, item string) (*Note, error) {
+	n, err := g.add(ctx, &Note{Text: item, Quantity: 1})
+	if err != nil {
+		return nil, err
+	}
+	return cloneNote(n), nil
+}
+
+// add adds n and returns it, or the note it was merged into.
+func (g *GroceryList) add(ctx context.Context, n *Note) (*Note, error) {
+	var events changes
+	defer g.notify(&events)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.validateNote(n); err != nil {
+		return nil, err
+	}
+	var notes []*Note
+	if !g.allowDuplicates() {
+		var err error
+		notes, err = g.Store.All(ctx)
+		if err != nil {
+			return nil, wrap("adding item", err)
+		}
+		if existing := g.lookup(notes, n.Text); existing != nil {
+			if g.AddMode != AddIncrementQuantity {
+				return existing, nil
+			}
+			if existing.Unit != n.Unit {
+				return nil, ErrUnitMismatch
+			}
+			prev := *existing
+			existing.Quantity += n.Quantity
+			if err := g.Store.Update(ctx, existing); err != nil {
+				return nil, wrap("adding item", err)
+			}
+			g.changed(&events, ChangeUpdate, existing, &prev)
+			return existing, nil
+		}
+	}
+	if err := g.checkRoom(ctx, notes, 1); err != nil {
+		return nil, wrap("adding item", err)
+	}
+	if err := g.Store.Create(ctx, n); err != nil {
+		return nil, wrap("adding item", err)
+	}
+	g.changed(&events, ChangeAdd, n, nil)
+	return n, nil
+}
+
+// checkRoom returns ErrListFull if adding more items would take the list
+// past MaxItems. The items are counted from the sync's latest fetch, or from
+// notes when the caller has fetched them already, before asking the store.
+// g.mu must be held.
+func (g *GroceryList) checkRoom(ctx context.Context, notes []*Note, more int) error {
+	if g.MaxItems <= 0 || more == 0 {
+		return nil
+	}
+	count := len(notes)
+	switch {
+	case g.synced(ctx) != nil:
+		count = len(g.synced(ctx))
+	case notes == nil:
+		var err error
+		if count, err = countNotes(ctx, g.Store); err != nil {
+			return err
+		}
+	}
+	if count+more > g.MaxItems {
+		return ErrListFull
+	}
+	return nil
+}
+
+// AddItems adds every item in one CreateMany call. Items already on the list
+// or repeated within items are handled according to AddMode, those to be
+// incremented with an Update each after the CreateMany.
+func (g *GroceryList) AddItems(ctx context.Context, items []string) error {
+	var events changes
+	defer g.notify(&events)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	valid := make([]string, len(items))
+	for i, item := range items {
+		var err error
+		if valid[i], err = g.validate(item); err != nil {
+			return err
+		}
+	}
+	items = valid
+
+	stored := map[string]*Note{}
+	var existing []*Note
+	if !g.allowDuplicates() {
+		var err error
+		existing, err = g.Store.All(ctx)
+		if err != nil {
+			return wrap("adding items", err)
+		}
+		for _, n := range existing {
+			if key := g.key(n.Text); stored[key] == nil {
+				stored[key] = n
+			}
+		}
+	}
+
+	increment := g.AddMode == AddIncrementQuantity
+	notes := []*Note{}
+	pending := map[string]*Note{}
+	var bumped []*Note
+	prevs := map[*Note]Note{}
+	for _, item := range items {
+		key := g.key(item)
+		if n := pending[key]; n != nil {
+			if increment {
+				n.Quantity++
+			}
+			continue
+		}
+		if n := stored[key]; n != nil {
+			if increment {
+				if _, ok := prevs[n]; !ok {
+					prevs[n] = *n
+					bumped = append(bumped, n)
+				}
+				n.Quantity++
+			}
+			continue
+		}
+		n := &Note{Text: item, Quantity: 1}
+		notes = append(notes, n)
+		if !g.allowDuplicates() {
+			pending[key] = n
+		}
+	}
+
+	if err := g.checkRoom(ctx, existing, len(notes)); err != nil {
+		return wrap("adding items", err)
+	}
+	var errs []error
+	if _, err := g.createMany(ctx, "adding", notes, &events); err != nil {
+		var be *BatchError
+		if !errors.As(err, &be) {
+			return err
+		}
+		errs = append(errs, err)
+	}
+	for _, n := range bumped {
+		if err := g.Store.Update(ctx, n); err != nil {
+			errs = append(errs, wrap("adding items", err))
+			continue
+		}
+		prev := prevs[n]
+		g.changed(&events, ChangeUpdate, n, &prev)
+	}
+	return errors.Join(errs...)
+}
+
+// createMany creates notes with one CreateMany call and returns how many
+// were created. A *BatchError from the store is returned, wrapped as a
+// failure of op, once the notes it didn't name have been recorded.
+func (g *GroceryList) createMany(ctx context.Context, op string, notes []*Note, events *changes) (int, error) {
+	if len(notes) == 0 {
+		return 0, nil
+	}
+	err := g.Store.CreateMany(ctx, notes)
+	var be *BatchError
+	if err != nil && !errors.As(err, &be) {
+		return 0, wrap(op+" items", err)
+	}
+	failed := map[int]bool{}
+	if be != nil {
+		for _, f := range be.Failures {
+			failed[f.Index] = true
+		}
+		err = wrap(op+" items", err)
+	}
+
+	created := 0
+	for i, n := range notes {
+		if !failed[i] {
+			g.changed(events, ChangeAdd, n, nil)
+			created++
+		}
+	}
+	return created, err
+}
+
+// MergeFrom adds every item of other that isn't already on the list, as
+// decided by DedupMode, in one CreateMany call, and returns how many items
+// were added or updated. Each list is fetched once. Added items keep their
+// quantity, category and purchased state.
+//
+// Items on both lists are skipped or, under MergeSumQuantities and
+// MergeMaxQuantity, have their quantities merged into the note on the list
+// with Update. Items on both lists in different units are left alone and
+// reported, once the rest are merged, in a *UnitMismatchError.
+func (g *GroceryList) MergeFrom(ctx context.Context, other *GroceryList) (int, error) {
+	if other == g {
+		return 0, nil
+	}
+	// Fetch other before locking g so that two lists merging from each
+	// other can't deadlock.
+	theirs, err := other.Snapshot(ctx)
+	if err != nil {
+		return 0, wrap("merging lists", err)
+	}
+
+	var events changes
+	defer g.notify(&events)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ours, err := g.Store.All(ctx)
+	if err != nil {
+		return 0, wrap("merging lists", err)
+	}
+	seen := map[string]*Note{}
+	for _, n := range ours {
+		if key := g.key(n.Text); seen[key] == nil {
+			seen[key] = n
+		}
+	}
+	notes := []*Note{}
+	added := map[*Note]bool{}
+	updated := []*Note{}
+	prevs := map[*Note]*Note{}
+	var mismatched []string
+	for _, n := range theirs {
+		key := g.key(n.Text)
+		target := seen[key]
+		if target == nil {
+			merged := cloneNote(n)
+			merged.ID = ""
+			notes = append(notes, merged)
+			seen[key] = merged
+			added[merged] = true
+			continue
+		}
+		if g.MergeMode == MergeSkipDuplicates {
+			continue
+		}
+		if target.Unit != n.Unit {
+			mismatched = append(mismatched, n.Text)
+			continue
+		}
+		qty := g.MergeMode.merge(target.Quantity, n.Quantity)
+		if qty == target.Quantity {
+			continue
+		}
+		if _, ok := prevs[target]; !ok && !added[target] {
+			prevs[target] = cloneNote(target)
+			updated = append(updated, target)
+		}
+		target.Quantity = qty
+	}
+
+	if len(notes) > 0 {
+		if err := g.Store.CreateMany(ctx, notes); err != nil {
+			return 0, wrap("merging lists", err)
+		}
+		for _, n := range notes {
+			g.changed(&events, ChangeAdd, n, nil)
+		}
+	}
+	merged := len(notes)
+	for _, n := range updated {
+		if err := g.Store.Update(ctx, n); err != nil {
+			return merged, wrap("merging lists", err)
+		}
+		g.changed(&events, ChangeUpdate, n, prevs[n])
+		merged++
+	}
+	if len(mismatched) > 0 {
+		return merged, &UnitMismatchError{Items: mismatched}
+	}
+	return merged, nil
+}
+
+// DiffTo compares the list with target, as decided by DedupMode, and
+// returns the items of target missing from the list and the items on the
+// list missing from target, each sorted and without duplicates. Blank
+// target items are ignored.
+func (g *GroceryList) DiffTo(ctx context.Context, target []string) (toAdd, toRemove []string, err error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	notes, err := g.Store.All(ctx)
+	if err != nil {
+		return nil, nil, wrap("diffing items", err)
+	}
+
+	toAdd, remove := g.diff(notes, target)
+	seen := map[string]bool{}
+	toRemove = []string{}
+	for _, n := range remove {
+		if key := g.key(n.Text); !seen[key] {
+			seen[key] = true
+			toRemove = append(toRemove, n.Text)
+		}
+	}
+	return toAdd, toRemove, nil
+}
+
+// SetItems replaces the whole list with items, a quantity of one each, in
+// a single ReplaceAll call rather than the creates and deletes of
+// ApplyTarget. Items repeated under DedupMode are kept once unless
+// duplicates are allowed, and no items clears the list. Nothing is replaced
+// if any item is invalid or, with MaxItems set, there are too many.
+func (g *GroceryList) SetItems(ctx context.Context, items []string) error {
+	var events changes
+	defer g.notify(&events)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	notes := []*Note{}
+	seen := map[string]bool{}
+	for _, item := range items {
+		item, err := g.validate(item)
+		if err != nil {
+			return err
+		}
+		key := g.key(item)
+		if seen[key] && !g.allowDuplicates() {
+			continue
+		}
+		seen[key] = true
+		notes = append(notes, &Note{Text: item, Quantity: 1})
+	}
+	if g.MaxItems > 0 && len(notes) > g.MaxItems {
+		return wrap("setting items", ErrListFull)
+	}
+
+	if err := g.Store.ReplaceAll(ctx, notes); err != nil {
+		return wrap("setting items", err)
+	}
+	g.changed(&events, ChangeClear, nil, nil)
+	for _, n := range notes {
+		g.changed(&events, ChangeAdd, n, nil)
+	}
+	return nil
+}
+
+// ApplyTarget makes the list hold exactly the items of target, as DiffTo
+// compares them: the missing items are added with one CreateMany call and
+// the rest removed with one DeleteMany call. It returns how many items were
+// added and removed; a failure of either call doesn't stop the other, and
+// their errors are joined.
+func (g *GroceryList) ApplyTarget(ctx context.Context, target []string) (added, removed int, err error) {
+	var events changes
+	defer g.notify(&events)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, item := range target {
+		if _, err := g.validate(item); err != nil && !errors.Is(err, ErrEmptyItem) {
+			return 0, 0, err
+		}
+	}
+
+	notes, err := g.Store.All(ctx)
+	if err != nil {
+		return 0, 0, wrap("applying items", err)
+	}
+	toAdd, remove := g.diff(notes, target)
+
+	create := make([]*Note, len(toAdd))
+	for i, item := range toAdd {
+		create[i] = &Note{Text: item, Quantity: 1}
+	}
+	added, addErr := g.createMany(ctx, "applying", create, &events)
+	removed, removeErr := g.deleteMany(ctx, "applying", remove, &events)
+	return added, removed, errors.Join(addErr, removeErr)
+}
+
+// diff returns the items of target missing from notes, sorted and free of
+// duplicates under DedupMode, and the notes whose items are missing from
+// target, sorted by text. Blank target items are ignored.
+func (g *GroceryList) diff(notes []*Note, target []string) ([]string, []*Note) {
+	want := map[string]bool{}
+	toAdd := []string{}
+	for _, item := range target {
+		item = g.normalize(item)
+		key := g.key(item)
+		if item == "" || want[key] {
+			continue
+		}
+		want[key] = true
+		if g.lookup(notes, item) == nil {
+			toAdd = append(toAdd, item)
+		}
+	}
+
+	remove := []*Note{}
+	for _, n := range notes {
+		if !want[g.key(n.Text)] {
+			remove = append(remove, n)
+		}
+	}
+
+	sort.Strings(toAdd)
+	sort.SliceStable(remove, func(i, j int) bool {
+		return remove[i].Text < remove[j].Text
+	})
+	return toAdd, remove
+}
+
+// validate returns item trimmed, or an error if it can't be added.
+func (g *GroceryList) validate(item string) (string, error) {
+	item = g.normalize(item)
+	if item == "" {
+		return "", ErrEmptyItem
+	}
+	if g.MaxLength > 0 && utf8.RuneCountInString(item) > g.MaxLength {
+		return "", ErrItemTooLong
+	}
+	return item, nil
+}
+
+// validateNote normalizes n's text and returns every reason, joined, that n
+// can't be added.
+func (g *GroceryList) validateNote(n *Note) error {
+	n.Text = g.normalize(n.Text)
+	err := n.Validate()
+	if g.MaxLength > 0 && utf8.RuneCountInString(n.Text) > g.MaxLength {
+		err = errors.Join(err, ErrItemTooLong)
+	}
+	return err
+}
+
+func (g *GroceryList) has(ctx context.Context, item string) (bool, error) {
+	notes, err := g.Store.All(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, n := range notes {
+		if g.same(n.Text, item) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetItem returns the note whose text is item, as the store matches it, or
+// ErrItemNotFound.
+func (g *GroceryList) GetItem(ctx context.Context, item string) (*Note, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	n, err := g.Store.Get(ctx, g.normalize(item))
+	if errors.Is(err, ErrItemNotFound) {
+		return nil, ErrItemNotFound
+	}
+	if err != nil {
+		return nil, wrap("getting item", err)
+	}
+	return n, nil
+}
+
+// GetItems returns the notes of those of items on the list, keyed by their
+// text as the store matches it, in one GetMany call. Items not on the list
+// are left out.
+func (g *GroceryList) GetItems(ctx context.Context, items []string) (map[string]*Note, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	notes, err := g.Store.GetMany(ctx, items)
+	if err != nil {
+		return nil, wrap("getting items", err)
+	}
+	return notes, nil
+}
+
+// Contains reports whether item is on the list, compared the same way as for
+// dedup. When the store is a Checker the check happens there.
+func (g *GroceryList) Contains(ctx context.Context, item string) (bool, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var ok bool
+	var err error
+	if checker, isChecker := g.Store.(Checker); isChecker {
+		ok, err = checker.Has(ctx, g.normalize(item))
+	} else {
+		ok, err = g.has(ctx, item)
+	}
+	return ok, wrap("checking item", err)
+}
+
+// RemoveItem deletes the note whose text is item, returning ErrItemNotFound
+// if there is no such note.
+func (g *GroceryList) RemoveItem(ctx context.Context, item string) error {
+	var events changes
+	defer g.notify(&events)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n, err := g.find(ctx, "removing item", item)
+	if err != nil {
+		return err
+	}
+	if err := g.Store.Delete(ctx, n); err != nil {
+		return wrap("removing item", err)
+	}
+	g.changed(&events, ChangeRemove, n, nil)
+	return nil
+}
+
+// MarkPurchased checks item off the list without removing it.
+func (g *GroceryList) MarkPurchased(ctx context.Context, item string) error {
+	var events changes
+	defer g.notify(&events)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n, err := g.find(ctx, "marking item purchased", item)
+	if err != nil {
+		return err
+	}
+	if n.Purchased {
+		return nil
+	}
+	prev := *n
+	n.Purchased = true
+	if err := g.Store.Update(ctx, n); err != nil {
+		return wrap("marking item purchased", err)
+	}
+	g.changed(&events, ChangeUpdate, n, &prev)
+	return nil
+}
+
+// MoveItem puts item in category with an Update, unless it is there already.
+// It returns ErrItemNotFound if there is no such item.
+func (g *GroceryList) MoveItem(ctx context.Context, item, category string) error {
+	var events changes
+	defer g.notify(&events)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n, err := g.find(ctx, "moving item", item)
+	if err != nil {
+		return err
+	}
+	if n.Category == category {
+		return nil
+	}
+	prev := *n
+	n.Category = category
+	if err := g.Store.Update(ctx, n); err != nil {
+		return wrap("moving item", err)
+	}
+	g.changed(&events, ChangeUpdate, n, &prev)
+	return nil
+}
+
+// Touch marks item as updated now, for RecentItems, without changing it. It
+// patches only the note's UpdatedAt, which stores that keep their own
+// timestamps set as they see fit. It returns ErrItemNotFound if there is no
+// such item.
+func (g *GroceryList) Touch(ctx context.Context, item string) error {
+	var events changes
+	defer g.notify(&events)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n, err := g.find(ctx, "touching item", item)
+	if err != nil {
+		return err
+	}
+	now := clockOr(g.Clock).Now().UTC()
+	if err := g.Store.UpdateFields(ctx, n.ID, map[string]interface{}{"updated_at": now}); err != nil {
+		return wrap("touching item", err)
+	}
+	prev := *n
+	n.UpdatedAt = now
+	g.changed(&events, ChangeUpdate, n, &prev)
+	return nil
+}
+
+// SetQuantity sets the quantity of item with UpdateFields, leaving the rest
+// of the note as the store has it. It returns ErrItemNotFound if there is no
+// such item.
+func (g *GroceryList) SetQuantity(ctx context.Context, item string, qty int) error {
+	var events changes
+	defer g.notify(&events)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n, err := g.find(ctx, "setting quantity", item)
+	if err != nil {
+		return err
+	}
+	if err := g.Store.UpdateFields(ctx, n.ID, map[string]interface{}{"quantity": qty}); err != nil {
+		return wrap("setting quantity", err)
+	}
+	prev := *n
+	n.Quantity = qty
+	g.changed(&events, ChangeUpdate, n, &prev)
+	return nil
+}
+
+// SetMeta sets key to value in the Meta of item, with UpdateFields sending
+// the whole of the new Meta. It returns ErrItemNotFound if there is no such
+// item.
+func (g *GroceryList) SetMeta(ctx context.Context, item, key, value string) error {
+	var events changes
+	defer g.notify(&events)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n, err := g.find(ctx, "setting meta", item)
+	if err != nil {
+		return err
+	}
+	meta := maps.Clone(n.Meta)
+	if meta == nil {
+		meta = map[string]string{}
+	}
+	meta[key] = value
+	if err := g.Store.UpdateFields(ctx, n.ID, map[string]interface{}{"meta": meta}); err != nil {
+		return wrap("setting meta", err)
+	}
+	prev := *n
+	n.Meta = meta
+	g.changed(&events, ChangeUpdate, n, &prev)
+	return nil
+}
+
+// GetMeta returns the value of key in the Meta of item and whether it is
+// set. It returns ErrItemNotFound if there is no such item.
+func (g *GroceryList) GetMeta(ctx context.Context, item, key string) (string, bool, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	n, err := g.find(ctx, "getting meta", item)
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := n.Meta[key]
+	return value, ok, nil
+}
+
+// ArchiveItem archives item, so that it drops off Items but can be brought
+// back with Unarchive, rather than deleting it. It returns ErrItemNotFound if
+// there is no such item.
+func (g *GroceryList) ArchiveItem(ctx context.Context, item string) error {
+	return g.setArchived(ctx, "archiving item", item, true)
+}
+
+// Unarchive returns an archived item to the list. It returns
+// ErrItemNotFound if there is no such item.
+func (g *GroceryList) Unarchive(ctx context.Context, item string) error {
+	return g.setArchived(ctx, "unarchiving item", item, false)
+}
+
+func (g *GroceryList) setArchived(ctx context.Context, op, item string, archived bool) error {
+	var events changes
+	defer g.notify(&events)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n, err := g.find(ctx, op, item)
+	if err != nil {
+		return err
+	}
+	if n.Archived == archived {
+		return nil
+	}
+	if err := g.Store.UpdateFields(ctx, n.ID, map[string]interface{}{"archived": archived}); err != nil {
+		return wrap(op, err)
+	}
+	prev := *n
+	n.Archived = archived
+	g.changed(&events, ChangeUpdate, n, &prev)
+	return nil
+}
+
+// ArchivedItems returns the archived items.
+func (g *GroceryList) ArchivedItems(ctx context.Context) ([]string, error) {
+	return g.ItemsWhere(ctx, func(n *Note) bool { return n.Archived })
+}
+
+// ClearPurchased deletes every purchased item in one DeleteMany call and
+// returns how many were removed. The items that couldn't be deleted are
+// named in the returned error.
+func (g *GroceryList) ClearPurchased(ctx context.Context) (int, error) {
+	var events changes
+	defer g.notify(&events)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	notes, err := g.Store.All(ctx)
+	if err != nil {
+		return 0, wrap("clearing purchased items", err)
+	}
+	purchased := []*Note{}
+	for _, n := range notes {
+		if n.Purchased {
+			purchased = append(purchased, n)
+		}
+	}
+	return g.deleteMany(ctx, "clearing purchased", purchased, &events)
+}
+
+// deleteMany deletes notes with one DeleteMany call and returns how many were
+// deleted. The notes the store couldn't delete are named in the returned
+// error, joined together, as failures of op.
+func (g *GroceryList) deleteMany(ctx context.Context, op string, notes []*Note, events *changes) (int, error) {
+	if len(notes) == 0 {
+		return 0, nil
+	}
+	err := g.Store.DeleteMany(ctx, notes)
+	var be *BatchError
+	if err != nil && !errors.As(err, &be) {
+		return 0, wrap(op+" items", err)
+	}
+	failed := map[int]string{}
+	if be != nil {
+		for _, f := range be.Failures {
+			failed[f.Index] = f.Message
+		}
+	}
+
+	removed := 0
+	var errs []error
+	for i, n := range notes {
+		if msg, ok := failed[i]; ok {
+			errs = append(errs, fmt.Errorf("grocery: %s item %q: %s", op, n.Text, msg))
+			continue
+		}
+		g.changed(events, ChangeRemove, n, nil)
+		removed++
+	}
+	return removed, errors.Join(errs...)
+}
+
+// ClearAll deletes every item. Stores that aren't a Clearer have every note
+// deleted in one DeleteMany call, and the notes that couldn't be deleted are
+// named in the returned error.
+func (g *GroceryList) ClearAll(ctx context.Context) error {
+	var events changes
+	defer g.notify(&events)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.clearAll(ctx, &events)
+}
+
+func (g *GroceryList) clearAll(ctx context.Context, events *changes) error {
+	if clearer, ok := g.Store.(Clearer); ok {
+		if err := clearer.DeleteAll(ctx); err != nil {
+			return wrap("clearing items", err)
+		}
+		g.changed(events, ChangeClear, nil, nil)
+		return nil
+	}
+	notes, err := g.Store.All(ctx)
+	if err != nil {
+		return wrap("clearing items", err)
+	}
+	_, err = g.deleteMany(ctx, "clearing", notes, events)
+	return err
+}
+
+// Snapshot returns every note on the list, for a later Restore.
+func (g *GroceryList) Snapshot(ctx context.Context) ([]*Note, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	notes, err := g.Store.All(ctx)
+	return notes, wrap("taking snapshot", err)
+}
+
+// Restore replaces the whole list with notes, usually taken by Snapshot. It
+// is destructive: every current item is deleted first, like ClearAll, and
+// the notes are then recreated in one CreateMany call, getting new IDs. If
+// the clear fails nothing is recreated, but some items may already be gone.
+func (g *GroceryList) Restore(ctx context.Context, notes []*Note) error {
+	var events changes
+	defer g.notify(&events)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.clearAll(ctx, &events); err != nil {
+		return err
+	}
+	if len(notes) == 0 {
+		return nil
+	}
+	restored := make([]*Note, len(notes))
+	for i, n := range notes {
+		restored[i] = cloneNote(n)
+		restored[i].ID = ""
+	}
+	if err := g.Store.CreateMany(ctx, restored); err != nil {
+		return wrap("restoring items", err)
+	}
+	for _, n := range restored {
+		g.changed(&events, ChangeAdd, n, nil)
+	}
+	return nil
+}
+
+// UpdateItem renames the note whose text is oldText to newText. It returns
+// ErrItemNotFound if there is no such note and, unless duplicates are
+// allowed, ErrDuplicateItem if newText is already on the list. An error
+// matching ErrConflict means the note changed while it was being renamed,
+// and the rename may be retried. newText is normalized and checked as
+// AddItem's item is.
+func (g *GroceryList) UpdateItem(ctx context.Context, oldText, newText string) error {
+	newText, err := g.validate(newText)
+	if err != nil {
+		return err
+	}
+
+	var events changes
+	defer g.notify(&events)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	notes, err := g.Store.All(ctx)
+	if err != nil {
+		return wrap("updating item", err)
+	}
+	n := g.lookup(notes, oldText)
+	if n == nil {
+		return ErrItemNotFound
+	}
+	if n.Text == newText {
+		return nil
+	}
+	if !g.allowDuplicates() {
+		for _, other := range notes {
+			if other != n && g.same(other.Text, newText) {
+				return ErrDuplicateItem
+			}
+		}
+	}
+
+	renamed := *n
+	renamed.Text = newText
+	if err := g.Store.Update(ctx, &renamed); err != nil {
+		return wrap("updating item", err)
+	}
+	g.changed(&events, ChangeUpdate, &renamed, n)
+	return nil
+}
+
+// find returns the note for item, or ErrItemNotFound. Store errors are
+// wrapped as failures of op.
+func (g *GroceryList) find(ctx context.Context, op, item string) (*Note, error) {
+	notes, err := g.Store.All(ctx)
+	if err != nil {
+		return nil, wrap(op, err)
+	}
+	if n := g.lookup(notes, item); n != nil {
+		return n, nil
+	}
+	return nil, ErrItemNotFound
+}
+
+// lookup returns the first of notes that is the same item as item, or nil.
+func (g *GroceryList) lookup(notes []*Note, item string) *Note {
+	for _, n := range notes {
+		if g.same(n.Text, item) {
+			return n
+		}
+	}
+	return nil
+}
+
+// Items returns the items in ascending Order, and otherwise in the order the
+// store returned them in. Archived items are left out, as they are by
+// ItemsSorted, ItemsBy and RecentItems.
+func (g *GroceryList) Items(ctx context.Context) ([]string, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.itemsBy(ctx, byOrder)
+}
+
+// AppendItems appends the items, as Items returns them, to dst and returns
+// the extended slice, so that a caller on a hot path can reuse one slice
+// across calls. On error dst is returned unchanged.
+func (g *GroceryList) AppendItems(ctx context.Context, dst []string) ([]string, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.appendItemsBy(ctx, dst, byOrder)
+}
+
+func byOrder(a, b *Note) bool {
+	return a.Order < b.Order
+}
+
+// Reorder puts items first, in the given sequence, followed by the rest of
+// the list in its current order, and saves each moved item's new Order
+// with Update. It returns ErrItemNotFound, before saving anything, if an
+// item isn't on the list.
+func (g *GroceryList) Reorder(ctx context.Context, items []string) error {
+	var events changes
+	defer g.notify(&events)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	notes, err := g.Store.All(ctx)
+	if err != nil {
+		return wrap("reordering items", err)
+	}
+	sort.SliceStable(notes, func(i, j int) bool {
+		return byOrder(notes[i], notes[j])
+	})
+
+	ordered := make([]*Note, 0, len(notes))
+	placed := map[*Note]bool{}
+	for _, item := range items {
+		n := g.lookup(notes, item)
+		if n == nil {
+			return ErrItemNotFound
+		}
+		if !placed[n] {
+			placed[n] = true
+			ordered = append(ordered, n)
+		}
+	}
+	for _, n := range notes {
+		if !placed[n] {
+			ordered = append(ordered, n)
+		}
+	}
+
+	for i, n := range ordered {
+		if n.Order == i+1 {
+			continue
+		}
+		prev := *n
+		n.Order = i + 1
+		if err := g.Store.Update(ctx, n); err != nil {
+			return wrap("reordering items", err)
+		}
+		g.changed(&events, ChangeUpdate, n, &prev)
+	}
+	return nil
+}
+
+// StreamItems sends each item as the store delivers it, streaming from
+// stores that are a Streamer and fetching everything at once from others.
+// The channels behave like those of Streamer. StreamItems doesn't hold the
+// list's lock while streaming.
+func (g *GroceryList) StreamItems(ctx context.Context) (<-chan string, <-chan error) {
+	items := make(chan string)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		defer close(items)
+
+		var notes <-chan *Note
+		var noteErrc <-chan error
+		if streamer, ok := g.Store.(Streamer); ok {
+			notes, noteErrc = streamer.AllStream(ctx)
+		} else {
+			all, err := g.Store.All(ctx)
+			if err != nil {
+				errc <- wrap("fetching items", err)
+				return
+			}
+			buffered := make(chan *Note, len(all))
+			for _, n := range all {
+				buffered <- n
+			}
+			close(buffered)
+			notes = buffered
+		}
+
+		for n := range notes {
+			select {
+			case items <- n.Text:
+			case <-ctx.Done():
+				errc <- ctx.Err()
+				return
+			}
+		}
+		if noteErrc != nil {
+			if err := <-noteErrc; err != nil {
+				errc <- wrap("fetching items", err)
+			}
+		}
+	}()
+
+	return items, errc
+}
+
+// ItemsSorted returns the items in alphabetical order, ignoring case. When
+// the store is a Sorter the sorting happens there, in its own collation.
+func (g *GroceryList) ItemsSorted(ctx context.Context) ([]string, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	byText := func(a, b *Note) bool {
+		return strings.ToLower(a.Text) < strings.ToLower(b.Text)
+	}
+	if sorter, ok := g.Store.(Sorter); ok {
+		notes, err := sorter.AllSorted(ctx, "text", false)
+		if err != nil {
+			err = wrap("fetching items", err)
+			if !g.FailOpen {
+				return []string{}, err
+			}
+			if notes = g.lastGood.get(ctx); notes == nil {
+				return []string{}, err
+			}
+			// The store can't sort them now, so they are sorted here.
+			notes = unarchived(notes)
+			sort.SliceStable(notes, func(i, j int) bool {
+				return byText(notes[i], notes[j])
+			})
+			return texts(notes), err
+		}
+		if g.FailOpen {
+			g.lastGood.set(ctx, notes)
+		}
+		return texts(unarchived(notes)), nil
+	}
+	return g.itemsBy(ctx, byText)
+}
+
+// ItemsBy returns the items ordered by less. Notes less considers equal keep
+// the order the store returned them in.
+func (g *GroceryList) ItemsBy(ctx context.Context, less func(a, b *Note) bool) ([]string, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.itemsBy(ctx, less)
+}
+
+func (g *GroceryList) itemsBy(ctx context.Context, less func(a, b *Note) bool) ([]string, error) {
+	return g.appendItemsBy(ctx, []string{}, less)
+}
+
+func (g *GroceryList) appendItemsBy(ctx context.Context, dst []string, less func(a, b *Note) bool) ([]string, error) {
+	notes, err := g.all(ctx)
+	if err != nil {
+		err = wrap("fetching items", err)
+		if !g.FailOpen {
+			return dst, err
+		}
+		if notes = g.lastGood.get(ctx); notes == nil {
+			return dst, err
+		}
+	} else if g.FailOpen {
+		g.lastGood.set(ctx, notes)
+	}
+
+	notes = unarchived(notes)
+	sort.SliceStable(notes, func(i, j int) bool {
+		return less(notes[i], notes[j])
+	})
+
+	dst = slices.Grow(dst, len(notes))
+	for _, n := range notes {
+		dst = append(dst, n.Text)
+	}
+
+	return dst, err
+}
+
+// CleanItems returns the items for printing: normalized with Normalizer,
+// each once under DedupMode, and sorted as ItemsSorted sorts them, ties
+// broken by exact text. Of the spellings of a duplicated item the one that
+// sorts first is kept, so the result doesn't depend on the store's order.
+// Archived items are left out, and an empty list gives an empty slice.
+func (g *GroceryList) CleanItems(ctx context.Context) ([]string, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	notes, err := g.all(ctx)
+	if err != nil {
+		return []string{}, wrap("fetching items", err)
+	}
+
+	clean := map[string]string{}
+	for _, n := range unarchived(notes) {
+		item := g.normalize(n.Text)
+		if item == "" {
+			continue
+		}
+		key := g.key(item)
+		if kept, ok := clean[key]; !ok || lessItem(item, kept) {
+			clean[key] = item
+		}
+	}
+	items := make([]string, 0, len(clean))
+	for _, item := range clean {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return lessItem(items[i], items[j])
+	})
+	return items, nil
+}
+
+// FindDuplicates returns the notes that are the same item, normalized and
+// under DedupMode, as another note, grouped by the key they are compared
+// by, in the order the store returned them. Items on the list once are left
+// out.
+func (g *GroceryList) FindDuplicates(ctx context.Context) (map[string][]*Note, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	notes, err := g.Store.All(ctx)
+	if err != nil {
+		return map[string][]*Note{}, wrap("fetching items", err)
+	}
+	return g.duplicates(notes), nil
+}
+
+func (g *GroceryList) duplicates(notes []*Note) map[string][]*Note {
+	groups := map[string][]*Note{}
+	for _, n := range notes {
+		key := g.key(n.Text)
+		groups[key] = append(groups[key], n)
+	}
+	for key, group := range groups {
+		if len(group) < 2 {
+			delete(groups, key)
+		}
+	}
+	return groups
+}
+
+// DedupeExisting deletes, in one DeleteMany call, every note FindDuplicates
+// would return except the first of each group, and returns how many were
+// deleted.
+func (g *GroceryList) DedupeExisting(ctx context.Context) (removed int, err error) {
+	var events changes
+	defer g.notify(&events)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	notes, err := g.Store.All(ctx)
+	if err != nil {
+		return 0, wrap("deduplicating items", err)
+	}
+	groups := g.duplicates(notes)
+	remove := []*Note{}
+	for _, n := range notes {
+		if group := groups[g.key(n.Text)]; len(group) > 0 && group[0] != n {
+			remove = append(remove, n)
+		}
+	}
+	return g.deleteMany(ctx, "deduplicating", remove, &events)
+}
+
+// lessItem orders items ignoring case, then by exact text.
+func lessItem(a, b string) bool {
+	if la, lb := strings.ToLower(a), strings.ToLower(b); la != lb {
+		return la < lb
+	}
+	return a < b
+}
+
+// unarchived returns the notes that aren't archived, in order.
+func unarchived(notes []*Note) []*Note {
+	active := make([]*Note, 0, len(notes))
+	for _, n := range notes {
+		if !n.Archived {
+			active = append(active, n)
+		}
+	}
+	return active
 }
 
-func (g *GroceryList) AddItem(item string) error {
-	return g.Store.Create(&Note{Text: item})
+// RecentItems returns the n most recently created or updated items, newest
+// first.
+func (g *GroceryList) RecentItems(ctx context.Context, n int) ([]string, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	items, err := g.itemsBy(ctx, func(a, b *Note) bool {
+		return lastChanged(a).After(lastChanged(b))
+	})
+	if err != nil {
+		return items, err
+	}
+	if n < len(items) {
+		items = items[:max(n, 0)]
+	}
+	return items, nil
 }
 
-func (g *GroceryList) Items() ([]string, error) {
-	notes, err := g.Store.All()
+func lastChanged(n *Note) time.Time {
+	if n.UpdatedAt.After(n.CreatedAt) {
+		return n.UpdatedAt
+	}
+	return n.CreatedAt
+}
+
+// Summary fetches the list once and aggregates it.
+func (g *GroceryList) Summary(ctx context.Context) (Summary, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	s := Summary{Quantities: map[string]int{}, ByCategory: map[string]int{}}
+	notes, err := g.Store.All(ctx)
+	if err != nil {
+		return s, wrap("summarizing items", err)
+	}
+
+	for _, n := range notes {
+		s.Total++
+		if n.Purchased {
+			s.Purchased++
+		} else {
+			s.Pending++
+		}
+		s.Quantities[n.Unit] += n.Quantity
+		category := n.Category
+		if category == "" {
+			category = Uncategorized
+		}
+		s.ByCategory[category]++
+	}
+
+	return s, nil
+}
+
+// ItemsByCategory groups the items by category. Items without a category
+// are grouped under Uncategorized.
+func (g *GroceryList) ItemsByCategory(ctx context.Context) (map[string][]string, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	notes, err := g.Store.All(ctx)
+	if err != nil {
+		return map[string][]string{}, wrap("fetching items", err)
+	}
+
+	groups := map[string][]string{}
+	for _, n := range notes {
+		category := n.Category
+		if category == "" {
+			category = Uncategorized
+		}
+		groups[category] = append(groups[category], n.Text)
+	}
+
+	return groups, nil
+}
+
+// Categories returns the categories of the unarchived items, each once and
+// sorted, with items that have none under Uncategorized. A Categorizer store
+// is asked for them, archived items and all; others have every item fetched.
+func (g *GroceryList) Categories(ctx context.Context) ([]string, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var categories []string
+	if categorizer, ok := g.Store.(Categorizer); ok {
+		var err error
+		if categories, err = categorizer.Categories(ctx); err != nil {
+			return []string{}, wrap("fetching categories", err)
+		}
+	} else {
+		notes, err := g.all(ctx)
+		if err != nil {
+			return []string{}, wrap("fetching categories", err)
+		}
+		for _, n := range unarchived(notes) {
+			categories = append(categories, n.Category)
+		}
+	}
+
+	seen := map[string]bool{}
+	distinct := []string{}
+	for _, category := range categories {
+		if category == "" {
+			category = Uncategorized
+		}
+		if !seen[category] {
+			seen[category] = true
+			distinct = append(distinct, category)
+		}
+	}
+	sort.Strings(distinct)
+	return distinct, nil
+}
+
+// Pending returns the items that haven't been purchased yet.
+func (g *GroceryList) Pending(ctx context.Context) ([]string, error) {
+	return g.ItemsWhere(ctx, func(n *Note) bool { return !n.Purchased })
+}
+
+// ItemsWhere returns the items whose notes pred accepts.
+func (g *GroceryList) ItemsWhere(ctx context.Context, pred func(*Note) bool) ([]string, error) {
+	notes, err := g.NotesWhere(ctx, pred)
 	if err != nil {
 		return []string{}, err
 	}
@@ -25,3 +1496,179 @@ func (g *GroceryList) Items() ([]string, error) {
 
 	return items, nil
 }
+
+// NotesWhere returns the notes pred accepts.
+func (g *GroceryList) NotesWhere(ctx context.Context, pred func(*Note) bool) ([]*Note, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	notes, err := g.Store.All(ctx)
+	if err != nil {
+		return []*Note{}, wrap("fetching items", err)
+	}
+
+	matched := []*Note{}
+	for _, n := range notes {
+		if pred(n) {
+			matched = append(matched, n)
+		}
+	}
+
+	return matched, nil
+}
+
+// Search returns the items containing query, ignoring case. An empty query
+// matches every item. When the store is a Searcher the search happens there.
+func (g *GroceryList) Search(ctx context.Context, query string) ([]string, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var notes []*Note
+	var err error
+	query = g.normalize(query)
+	if searcher, ok := g.Store.(Searcher); ok && query != "" {
+		notes, err = searcher.Search(ctx, query)
+	} else {
+		notes, err = g.Store.All(ctx)
+	}
+	if err != nil {
+		return []string{}, wrap("fetching items", err)
+	}
+
+	query = strings.ToLower(query)
+	items := []string{}
+	for _, n := range notes {
+		if strings.Contains(strings.ToLower(n.Text), query) {
+			items = append(items, n.Text)
+		}
+	}
+
+	return items, nil
+}
+
+func (g *GroceryList) ItemsWithQuantity(ctx context.Context) ([]Item, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	notes, err := g.Store.All(ctx)
+	if err != nil {
+		return []Item{}, wrap("fetching items", err)
+	}
+
+	items := make([]Item, len(notes))
+	for i := range notes {
+		items[i] = Item{Text: notes[i].Text, Quantity: notes[i].Quantity, Unit: notes[i].Unit}
+	}
+
+	return items, nil
+}
+
+// Count returns how many items there are, asking a Counter store to count
+// them or a Pager store for the total of a one-item page. Other stores have
+// every item fetched and counted.
+func (g *GroceryList) Count(ctx context.Context) (int, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	n, err := countNotes(ctx, g.Store)
+	return n, wrap("counting items", err)
+}
+
+// Healthy reports whether the store is reachable.
+func (g *GroceryList) Healthy(ctx context.Context) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return wrap("checking health", g.Store.Ping(ctx))
+}
+
+// Close closes the store if it is an io.Closer, once the calls already in
+// progress have finished. Other stores need no closing.
+func (g *GroceryList) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return closeStore(g.Store)
+}
+
+// ItemsPage returns up to limit items starting at offset, plus the total
+// number of items. Stores that aren't a Pager are fetched in full and sliced.
+// A negative limit or offset counts as zero.
+func (g *GroceryList) ItemsPage(ctx context.Context, limit, offset int) ([]string, int, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if limit < 0 {
+		limit = 0
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	var notes []*Note
+	var total int
+	if pager, ok := g.Store.(Pager); ok {
+		var err error
+		notes, total, err = pager.AllPage(ctx, limit, offset)
+		if err != nil {
+			return []string{}, 0, wrap("fetching items", err)
+		}
+	} else {
+		all, err := g.Store.All(ctx)
+		if err != nil {
+			return []string{}, 0, wrap("fetching items", err)
+		}
+		total = len(all)
+		start, end := pageBounds(total, limit, offset)
+		notes = all[start:end]
+	}
+
+	items := make([]string, len(notes))
+	for i := range notes {
+		items[i] = notes[i].Text
+	}
+
+	return items, total, nil
+}
+
+// ItemsIterator returns a function that yields the items one at a time,
+// fetching them from the store pageSize at a time as they are needed, 100 when
+// pageSize is zero. The function returns ok false once every item has been
+// yielded, or the error if fetching a page fails.
+func (g *GroceryList) ItemsIterator(ctx context.Context, pageSize int) func() (item string, ok bool, err error) {
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	var page []string
+	var err error
+	offset, total := 0, -1
+	return func() (string, bool, error) {
+		if err != nil {
+			return "", false, err
+		}
+		if len(page) == 0 {
+			if total >= 0 && offset >= total {
+				return "", false, nil
+			}
+			page, total, err = g.ItemsPage(ctx, pageSize, offset)
+			if err != nil {
+				return "", false, err
+			}
+			if len(page) == 0 {
+				total = offset
+				return "", false, nil
+			}
+			offset += len(page)
+		}
+		item := page[0]
+		page = page[1:]
+		return item, true, nil
+	}
+}
+
+// wrap annotates an error from the store with the operation that failed.
+func wrap(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("grocery: %s: %w", op, err)
+}
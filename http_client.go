@@ -0,0 +1,1131 @@
+package grocery
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultPageSize = 100
+
+// DefaultMaxResponseBytes is the MaxResponseBytes NewHTTPClient sets.
+const DefaultMaxResponseBytes = 10 << 20
+
+// HTTPClient talks to a JSON REST backend rooted at BaseURL. Requests are
+// sent with Client, or http.DefaultClient when Client is nil, and failed
+// requests are retried according to Retry. All fetches PageSize notes per
+// request, 100 when zero.
+//
+// Auth, when set, is called on every outgoing request, including retries,
+// to attach credentials. It is a function rather than a fixed token so that
+// tokens can be refreshed as they rotate.
+//
+// UserAgent, when set, is sent as the User-Agent of every request, and
+// Header holds further headers sent with every request, such as a tenant
+// ID. Headers the client sets itself take precedence over Header.
+//
+// Logger, when set, is called after every request, including retries, with
+// its method, URL, response status and latency. The status is 0 when no
+// response was received. Logf, when set, is told when the client falls back
+// to listing notes because the backend lacks the count or categories
+// endpoint.
+//
+// Creates carry an Idempotency-Key header, the same across retries of one
+// call, so the backend can drop duplicates. Keys come from IdempotencyKey,
+// or are random UUIDs when it is nil.
+//
+// Every request carries an X-Request-ID header, the same across retries of
+// one call, for tracing it through the backend. IDs come from RequestID, then
+// from ContextWithRequestID, or are random UUIDs when neither has one.
+//
+// Calls made with a ctx from ContextWithTenant carry its tenant in an
+// X-Tenant header, and their pages are cached per tenant. Calls without one
+// fail with ErrNoTenant rather than reach the backend's shared namespace,
+// unless NoTenant opts a single-tenant client out.
+//
+// Responses may be gzipped and are decompressed transparently. Once the
+// backend has shown it understands gzip, by sending a gzipped response or an
+// Accept-Encoding header that includes gzip, CreateMany bodies of at least
+// CompressThreshold bytes are gzipped too. Zero never compresses requests.
+//
+// RequestTimeout, when set, limits each attempt at a request, so that one
+// slow response leaves time to retry within ctx. An attempt that runs out
+// of time fails with ErrRequestTimeout, which is retried by default.
+// Streams from AllStream aren't limited.
+//
+// WithRateLimit limits every request, retries included, to a rate shared by
+// all the client's calls, each blocking within its ctx until it may go. By
+// default requests aren't limited.
+//
+// MaxConcurrency, when set, bounds how many requests are in flight at once,
+// from a request being sent until its response is read. Excess requests
+// block within their ctx until another finishes. Unlike WithRateLimit this
+// bounds concurrency rather than rate; a stream from AllStream holds its
+// slot until it ends.
+//
+// Codec, when set, encodes request bodies and decodes responses in place of
+// JSONCodec. A response labelled application/json is decoded as JSON when
+// the Codec has another content type. AllStream can only decode with
+// JSONCodec incrementally, so with another Codec it decodes the whole
+// response before streaming its notes.
+//
+// ContentType, when set to FormContentType, sends Create bodies as form
+// values named after the note's JSON fields rather than with the Codec.
+// Other values must be the Codec's content type. Responses are decoded as
+// usual.
+//
+// MaxResponseBytes limits the size of the responses decoded whole, which
+// fail with ErrResponseTooLarge rather than being read past the limit.
+// The body an HTTPError keeps is cut short at the limit, or at 64 KiB.
+// NewHTTPClient sets DefaultMaxResponseBytes; zero is unlimited. JSON
+// streams from AllStream aren't limited.
+//
+// Requests follow up to MaxRedirects redirects, 10 when zero, as
+// http.Client does. With NoRedirects, or past the limit, a redirect fails
+// with ErrRedirectRefused giving its target, which isn't retried. A Client
+// with a CheckRedirect of its own keeps it, and NoRedirects and
+// MaxRedirects are then ignored.
+//
+// Clock, when set, replaces the wall clock for the waits between retries,
+// rate limiting, Retry-After dates and the latency given to Logger.
+//
+// Pages fetched by All and AllPage are remembered along with their ETag,
+// which is sent back as If-None-Match so that an unchanged page can be
+// answered with 304 Not Modified and served from memory.
+type HTTPClient struct {
+	BaseURL           string
+	Client            *http.Client
+	Retry             RetryPolicy
+	PageSize          int
+	Auth              func(*http.Request) error
+	UserAgent         string
+	Header            http.Header
+	Logger            func(method, url string, status int, dur time.Duration)
+	Logf              func(format string, args ...interface{})
+	IdempotencyKey    func() string
+	CompressThreshold int
+	RequestTimeout    time.Duration
+	RequestID         func(ctx context.Context) string
+	Codec             Codec
+	Clock             Clock
+	MaxResponseBytes  int64
+	NoRedirects       bool
+	MaxRedirects      int
+	MaxConcurrency    int
+	NoTenant          bool
+	ContentType       string
+
+	acceptsGzip atomic.Bool
+	limiter     *rateLimiter
+	slotsOnce   sync.Once
+	slots       chan struct{}
+
+	pagesMu sync.Mutex
+	pages   map[string]cachedPage
+}
+
+// cachedPage is a page of notes the backend sent with an ETag.
+type cachedPage struct {
+	etag  string
+	notes []*Note
+	total int
+}
+
+type Option func(*HTTPClient)
+
+// WithHTTPClient sets the *http.Client used to send requests, for example one
+// with a tuned transport or timeout.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *HTTPClient) {
+		c.Client = hc
+	}
+}
+
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *HTTPClient) {
+		c.Retry = p
+	}
+}
+
+func WithPageSize(n int) Option {
+	return func(c *HTTPClient) {
+		c.PageSize = n
+	}
+}
+
+func WithAuth(auth func(*http.Request) error) Option {
+	return func(c *HTTPClient) {
+		c.Auth = auth
+	}
+}
+
+func WithUserAgent(ua string) Option {
+	return func(c *HTTPClient) {
+		c.UserAgent = ua
+	}
+}
+
+// WithHeader adds value to the header key sent with every request.
+func WithHeader(key, value string) Option {
+	return func(c *HTTPClient) {
+		if c.Header == nil {
+			c.Header = http.Header{}
+		}
+		c.Header.Add(key, value)
+	}
+}
+
+func WithLogger(logger func(method, url string, status int, dur time.Duration)) Option {
+	return func(c *HTTPClient) {
+		c.Logger = logger
+	}
+}
+
+func WithLogf(logf func(format string, args ...interface{})) Option {
+	return func(c *HTTPClient) {
+		c.Logf = logf
+	}
+}
+
+func WithCompression(threshold int) Option {
+	return func(c *HTTPClient) {
+		c.CompressThreshold = threshold
+	}
+}
+
+func WithRequestTimeout(d time.Duration) Option {
+	return func(c *HTTPClient) {
+		c.RequestTimeout = d
+	}
+}
+
+func WithRequestID(f func(ctx context.Context) string) Option {
+	return func(c *HTTPClient) {
+		c.RequestID = f
+	}
+}
+
+// WithRateLimit allows perSecond requests a second on average, and bursts of
+// up to burst requests at once. A perSecond of zero or less removes the limit.
+func WithRateLimit(perSecond float64, burst int) Option {
+	return func(c *HTTPClient) {
+		c.limiter = nil
+		if perSecond > 0 {
+			c.limiter = newRateLimiter(perSecond, burst)
+		}
+	}
+}
+
+func WithMaxConcurrency(n int) Option {
+	return func(c *HTTPClient) {
+		c.MaxConcurrency = n
+	}
+}
+
+// WithoutTenant lets calls without a tenant from ContextWithTenant reach the
+// backend's shared namespace instead of failing with ErrNoTenant.
+func WithoutTenant() Option {
+	return func(c *HTTPClient) {
+		c.NoTenant = true
+	}
+}
+
+func WithClock(clock Clock) Option {
+	return func(c *HTTPClient) {
+		c.Clock = clock
+	}
+}
+
+func WithMaxResponseBytes(n int64) Option {
+	return func(c *HTTPClient) {
+		c.MaxResponseBytes = n
+	}
+}
+
+// WithRedirects follows up to max redirects, or refuses them all when max
+// is zero. It has no effect with a Client that has its own CheckRedirect.
+func WithRedirects(max int) Option {
+	return func(c *HTTPClient) {
+		c.NoRedirects = max <= 0
+		c.MaxRedirects = max
+	}
+}
+
+func WithContentType(contentType string) Option {
+	return func(c *HTTPClient) {
+		c.ContentType = contentType
+	}
+}
+
+func WithCodec(codec Codec) Option {
+	return func(c *HTTPClient) {
+		c.Codec = codec
+	}
+}
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying id, which an
+// HTTPClient sends as the X-Request-ID of calls made with it.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the id ctx carries from ContextWithRequestID,
+// or "" if it has none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+type tenantKey struct{}
+
+// ContextWithTenant returns a copy of ctx carrying tenant, which an
+// HTTPClient sends as the X-Tenant of calls made with it.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant ctx carries from ContextWithTenant,
+// or "" if it has none.
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// BearerToken returns an Auth function sending token as a bearer token.
+func BearerToken(token string) func(*http.Request) error {
+	return func(r *http.Request) error {
+		r.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+func NewHTTPClient(baseURL string, opts ...Option) *HTTPClient {
+	c := &HTTPClient{BaseURL: baseURL, MaxResponseBytes: DefaultMaxResponseBytes}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Create posts n and fills it in from the created note the backend returns,
+// including its ID.
+func (c *HTTPClient) Create(ctx context.Context, n *Note) error {
+	form := c.ContentType == FormContentType
+	if !form && c.ContentType != "" && c.ContentType != c.codec().ContentType() {
+		return fmt.Errorf("grocery: unsupported content type %q", c.ContentType)
+	}
+	_, err := c.do(ctx, request{method: http.MethodPost, path: "/notes", header: c.idempotent(), in: n, out: n, form: form})
+	return err
+}
+
+// CreateMany posts notes to the batch endpoint in one request and takes the
+// IDs of the created notes from those the backend sends back, in the order
+// posted and without the rejected ones. The backend may accept some notes and
+// reject others; any rejections are returned as a *BatchError.
+func (c *HTTPClient) CreateMany(ctx context.Context, notes []*Note) error {
+	var result struct {
+		Notes  []*Note        `json:"notes"`
+		Errors []BatchFailure `json:"errors"`
+	}
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/notes/batch", header: c.idempotent(), in: notes, out: &result, compress: true}); err != nil {
+		return err
+	}
+	rejected := map[int]bool{}
+	for _, f := range result.Errors {
+		rejected[f.Index] = true
+	}
+	if len(result.Notes) == len(notes)-len(rejected) {
+		created := result.Notes
+		for i, n := range notes {
+			if !rejected[i] {
+				n.ID, created = created[0].ID, created[1:]
+			}
+		}
+	}
+	if len(result.Errors) > 0 {
+		return &BatchError{Failures: result.Errors}
+	}
+	return nil
+}
+
+// ReplaceAll puts notes to /notes in one request, replacing every note on
+// the backend, and takes their IDs from the notes it sends back. It fails if
+// the backend sends back a different number of notes, as their IDs can't be
+// matched up.
+func (c *HTTPClient) ReplaceAll(ctx context.Context, notes []*Note) error {
+	if notes == nil {
+		notes = []*Note{}
+	}
+	stored := []*Note{}
+	if _, err := c.do(ctx, request{method: http.MethodPut, path: "/notes", in: notes, out: &stored, compress: true}); err != nil {
+		return err
+	}
+	if len(stored) != len(notes) {
+		return fmt.Errorf("grocery: replacing notes: sent %d notes but the backend stored %d", len(notes), len(stored))
+	}
+	for i, n := range stored {
+		notes[i].ID = n.ID
+	}
+	return nil
+}
+
+// All fetches every note, one page at a time.
+func (c *HTTPClient) All(ctx context.Context) ([]*Note, error) {
+	size := c.PageSize
+	if size <= 0 {
+		size = defaultPageSize
+	}
+
+	notes := []*Note{}
+	for {
+		page, total, err := c.AllPage(ctx, size, len(notes))
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, page...)
+		if len(page) == 0 || len(notes) >= total {
+			return notes, nil
+		}
+	}
+}
+
+// Count asks the backend's count endpoint how many notes there are. Backends
+// without one are asked for a one-note page instead, whose total is the
+// count, and the fallback is reported to Logf.
+func (c *HTTPClient) Count(ctx context.Context) (int, error) {
+	var result struct {
+		Count int `json:"count"`
+	}
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/notes/count", out: &result})
+	var he *HTTPError
+	if errors.As(err, &he) && unsupported(he.StatusCode) {
+		c.logf("grocery: %s has no count endpoint (status %d), falling back to listing notes", c.BaseURL, he.StatusCode)
+		_, total, err := c.AllPage(ctx, 1, 0)
+		if err != nil {
+			return 0, err
+		}
+		return total, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return result.Count, nil
+}
+
+// Categories asks the backend's categories endpoint for the categories in
+// use. Backends without one get every note fetched instead, and the fallback
+// is reported to Logf.
+func (c *HTTPClient) Categories(ctx context.Context) ([]string, error) {
+	categories := []string{}
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/notes/categories", out: &categories})
+	var he *HTTPError
+	if errors.As(err, &he) && unsupported(he.StatusCode) {
+		c.logf("grocery: %s has no categories endpoint (status %d), falling back to listing notes", c.BaseURL, he.StatusCode)
+		notes, err := c.All(ctx)
+		if err != nil {
+			return nil, err
+		}
+		categories = categories[:0]
+		for _, n := range notes {
+			categories = append(categories, n.Category)
+		}
+		return categories, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (c *HTTPClient) logf(format string, args ...interface{}) {
+	if c.Logf != nil {
+		c.Logf(format, args...)
+	}
+}
+
+func unsupported(code int) bool {
+	return code == http.StatusNotFound || code == http.StatusMethodNotAllowed || code == http.StatusNotImplemented
+}
+
+// AllPage fetches up to limit notes starting at offset, along with the total
+// number of notes from the X-Total-Count header. A backend that doesn't send
+// the header is assumed to have returned everything.
+func (c *HTTPClient) AllPage(ctx context.Context, limit, offset int) ([]*Note, int, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+	key := TenantFromContext(ctx) + "?" + query.Encode()
+
+	c.pagesMu.Lock()
+	cached, ok := c.pages[key]
+	c.pagesMu.Unlock()
+	var header http.Header
+	if ok {
+		header = http.Header{"If-None-Match": {cached.etag}}
+	}
+
+	notes := []*Note{}
+	header, err := c.do(ctx, request{method: http.MethodGet, path: "/notes", query: query, header: header, out: &notes})
+	var he *HTTPError
+	if ok && errors.As(err, &he) && he.StatusCode == http.StatusNotModified {
+		return cloneNotes(cached.notes), cached.total, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total := offset + len(notes)
+	if h := header.Get("X-Total-Count"); h != "" {
+		total, err = strconv.Atoi(h)
+		if err != nil {
+			return nil, 0, fmt.Errorf("grocery: bad X-Total-Count %q: %w", h, err)
+		}
+	}
+
+	c.pagesMu.Lock()
+	if etag := header.Get("ETag"); etag != "" {
+		if c.pages == nil {
+			c.pages = map[string]cachedPage{}
+		}
+		c.pages[key] = cachedPage{etag, cloneNotes(notes), total}
+	} else {
+		delete(c.pages, key)
+	}
+	c.pagesMu.Unlock()
+	return notes, total, nil
+}
+
+// Get asks the backend for the notes with text and returns the first.
+func (c *HTTPClient) Get(ctx context.Context, text string) (*Note, error) {
+	q := url.Values{}
+	q.Set("text", text)
+
+	notes := []*Note{}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/notes", query: q, out: &notes}); err != nil {
+		return nil, err
+	}
+	if len(notes) == 0 {
+		return nil, ErrItemNotFound
+	}
+	return notes[0], nil
+}
+
+// GetMany fetches the notes with texts in one request, naming every text in
+// the query.
+func (c *HTTPClient) GetMany(ctx context.Context, texts []string) (map[string]*Note, error) {
+	found := map[string]*Note{}
+	if len(texts) == 0 {
+		return found, nil
+	}
+	q := url.Values{"text": texts}
+
+	notes := []*Note{}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/notes", query: q, out: &notes}); err != nil {
+		return nil, err
+	}
+	for _, n := range notes {
+		if _, ok := found[n.Text]; !ok {
+			found[n.Text] = n
+		}
+	}
+	return found, nil
+}
+
+// Search asks the backend for the notes matching query.
+func (c *HTTPClient) Search(ctx context.Context, query string) ([]*Note, error) {
+	q := url.Values{}
+	q.Set("q", query)
+
+	notes := []*Note{}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/notes", query: q, out: &notes}); err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// AllSorted fetches every note in a single request, sorted by the backend.
+func (c *HTTPClient) AllSorted(ctx context.Context, field string, desc bool) ([]*Note, error) {
+	if err := checkSortField(field); err != nil {
+		return nil, err
+	}
+	order := "asc"
+	if desc {
+		order = "desc"
+	}
+	q := url.Values{}
+	q.Set("sort", field)
+	q.Set("order", order)
+
+	notes := []*Note{}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/notes", query: q, out: &notes}); err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// AllStream fetches every note in a single request and decodes the response
+// incrementally, sending each note as it arrives. The error channel receives
+// at most one error and is closed, like the note channel, once the response
+// has been read. Cancelling ctx stops the stream and closes the response.
+func (c *HTTPClient) AllStream(ctx context.Context) (<-chan *Note, <-chan error) {
+	notes := make(chan *Note)
+	errc := make(chan error, 1)
+
+	resp, err := c.roundTrip(ctx, request{method: http.MethodGet, path: "/notes"}, nil)
+	if err != nil {
+		close(notes)
+		errc <- err
+		close(errc)
+		return notes, errc
+	}
+
+	go func() {
+		defer close(errc)
+		defer close(notes)
+		defer resp.Body.Close()
+
+		codec := c.responseCodec(resp.Header)
+		if _, ok := codec.(JSONCodec); !ok {
+			c.streamDecoded(ctx, codec, resp.Body, notes, errc)
+			return
+		}
+		dec := json.NewDecoder(resp.Body)
+		if tok, err := dec.Token(); err != nil {
+			errc <- err
+			return
+		} else if tok != json.Delim('[') {
+			errc <- fmt.Errorf("grocery: expected a JSON array of notes but got %v", tok)
+			return
+		}
+		for dec.More() {
+			n := &Note{}
+			if err := dec.Decode(n); err != nil {
+				errc <- err
+				return
+			}
+			select {
+			case notes <- n:
+			case <-ctx.Done():
+				errc <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return notes, errc
+}
+
+// streamDecoded decodes body whole with codec and sends its notes, for
+// AllStream.
+func (c *HTTPClient) streamDecoded(ctx context.Context, codec Codec, body io.Reader, notes chan<- *Note, errc chan<- error) {
+	all := []*Note{}
+	if err := c.decode(body, codec, &all); err != nil {
+		errc <- err
+		return
+	}
+	for _, n := range all {
+		select {
+		case notes <- n:
+		case <-ctx.Done():
+			errc <- ctx.Err()
+			return
+		}
+	}
+}
+
+// Update replaces the note with n's ID with n. A note with a Version is sent
+// with it as If-Match, so that the backend refuses the update, matching
+// ErrConflict, if the note has changed since. The note the backend answers
+// with, if any, is decoded into n, carrying its new Version.
+func (c *HTTPClient) Update(ctx context.Context, n *Note) error {
+	path, err := notePath(n)
+	if err != nil {
+		return err
+	}
+	var header http.Header
+	if n.Version > 0 {
+		header = http.Header{"If-Match": {strconv.Quote(strconv.Itoa(n.Version))}}
+	}
+	_, err = c.do(ctx, request{method: http.MethodPut, path: path, header: header, in: n, out: n})
+	return err
+}
+
+// UpdateFields sends fields as a PATCH to the note with ID id.
+func (c *HTTPClient) UpdateFields(ctx context.Context, id string, fields map[string]interface{}) error {
+	path, err := notePath(&Note{ID: id})
+	if err != nil {
+		return err
+	}
+	_, err = c.do(ctx, request{method: http.MethodPatch, path: path, in: fields})
+	return err
+}
+
+func (c *HTTPClient) Delete(ctx context.Context, n *Note) error {
+	path, err := notePath(n)
+	if err != nil {
+		return err
+	}
+	_, err = c.do(ctx, request{method: http.MethodDelete, path: path})
+	return err
+}
+
+// Ping checks the backend's health endpoint. It is not retried, so a down
+// backend fails the first attempt, within ctx and the Client's timeout.
+func (c *HTTPClient) Ping(ctx context.Context) error {
+	_, err := c.attempt(ctx, request{method: http.MethodGet, path: "/health"}, nil)
+	return err
+}
+
+// DeleteMany posts the IDs of notes to the batch delete endpoint in one
+// request. The backend may delete some notes and not others; any failures
+// are returned as a *BatchError.
+func (c *HTTPClient) DeleteMany(ctx context.Context, notes []*Note) error {
+	ids := make([]string, len(notes))
+	for i, n := range notes {
+		if n.ID == "" {
+			return fmt.Errorf("grocery: note %q has no ID", n.Text)
+		}
+		ids[i] = n.ID
+	}
+	var result struct {
+		Errors []BatchFailure `json:"errors"`
+	}
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/notes/batch/delete", in: ids, out: &result}); err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return &BatchError{Failures: result.Errors}
+	}
+	return nil
+}
+
+func notePath(n *Note) (string, error) {
+	if n.ID == "" {
+		return "", fmt.Errorf("grocery: note %q has no ID", n.Text)
+	}
+	return "/notes/" + url.PathEscape(n.ID), nil
+}
+
+// idempotent returns the headers identifying one logical create.
+func (c *HTTPClient) idempotent() http.Header {
+	key := newUUID
+	if c.IdempotencyKey != nil {
+		key = c.IdempotencyKey
+	}
+	return http.Header{"Idempotency-Key": {key()}}
+}
+
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Close closes the idle connections of Client, if set.
+func (c *HTTPClient) Close() error {
+	if c.Client != nil {
+		c.Client.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (c *HTTPClient) requestID(ctx context.Context) string {
+	if c.RequestID != nil {
+		if id := c.RequestID(ctx); id != "" {
+			return id
+		}
+	}
+	if id := RequestIDFromContext(ctx); id != "" {
+		return id
+	}
+	return newUUID()
+}
+
+func (c *HTTPClient) codec() Codec {
+	if c.Codec == nil {
+		return JSONCodec{}
+	}
+	return c.Codec
+}
+
+// defaultMaxRedirects is how many redirects are followed when MaxRedirects
+// is zero, as many as http.Client follows.
+const defaultMaxRedirects = 10
+
+func (c *HTTPClient) httpClient() *http.Client {
+	hc := c.Client
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	if hc.CheckRedirect != nil {
+		return hc
+	}
+	// A copy, as the Client may be shared with code expecting its own
+	// redirect policy.
+	copied := *hc
+	copied.CheckRedirect = c.checkRedirect
+	return &copied
+}
+
+func (c *HTTPClient) checkRedirect(req *http.Request, via []*http.Request) error {
+	if c.NoRedirects {
+		return fmt.Errorf("%w to %s", ErrRedirectRefused, req.URL)
+	}
+	max := c.MaxRedirects
+	if max <= 0 {
+		max = defaultMaxRedirects
+	}
+	if len(via) > max {
+		return fmt.Errorf("%w to %s after %d redirects", ErrRedirectRefused, req.URL, max)
+	}
+	return nil
+}
+
+type BatchFailure struct {
+	Index   int    `json:"index"`
+	Message string `json:"message"`
+}
+
+// BatchError reports which notes of a batch, by index, were not created or
+// deleted.
+type BatchError struct {
+	Failures []BatchFailure
+}
+
+func (e *BatchError) Error() string {
+	msgs := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		msgs[i] = fmt.Sprintf("index %d: %s", f.Index, f.Message)
+	}
+	return fmt.Sprintf("grocery: %d notes in batch failed: %s", len(e.Failures), strings.Join(msgs, "; "))
+}
+
+// request describes one call to the backend. in, when non-nil, is sent as
+// the JSON request body and a successful JSON response is decoded into out,
+// when non-nil. header is added to every attempt. compress allows a large
+// body to be gzipped.
+type request struct {
+	method   string
+	path     string
+	query    url.Values
+	header   http.Header
+	in       interface{}
+	out      interface{}
+	compress bool
+	form     bool
+}
+
+// do sends r, retrying according to c.Retry, and returns the headers of the
+// successful response.
+func (c *HTTPClient) do(ctx context.Context, r request) (http.Header, error) {
+	var body []byte
+	if r.in != nil {
+		marshal := c.codec().Marshal
+		if r.form {
+			marshal = formEncode
+			r.header = r.header.Clone()
+			if r.header == nil {
+				r.header = http.Header{}
+			}
+			r.header.Set("Content-Type", FormContentType)
+		}
+		b, err := marshal(r.in)
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+	if r.compress && c.CompressThreshold > 0 && len(body) >= c.CompressThreshold && c.acceptsGzip.Load() {
+		b, err := gzipBytes(body)
+		if err != nil {
+			return nil, err
+		}
+		body = b
+		r.header = r.header.Clone()
+		if r.header == nil {
+			r.header = http.Header{}
+		}
+		r.header.Set("Content-Encoding", "gzip")
+	}
+
+	// Fix the request ID now so that every attempt sends the same one.
+	ctx = ContextWithRequestID(ctx, c.requestID(ctx))
+	var header http.Header
+	err := c.Retry.do(ctx, c.Clock, func() error {
+		var err error
+		header, err = c.attempt(ctx, r, body)
+		return err
+	})
+	return header, err
+}
+
+// attempt sends r once, within c.RequestTimeout if set.
+func (c *HTTPClient) attempt(ctx context.Context, r request, body []byte) (http.Header, error) {
+	if c.RequestTimeout <= 0 {
+		return c.send(ctx, r, body)
+	}
+	actx, cancel := context.WithTimeout(ctx, c.RequestTimeout)
+	defer cancel()
+	header, err := c.send(actx, r, body)
+	if err != nil && ctx.Err() == nil && errors.Is(actx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("%w after %v: %s %s", ErrRequestTimeout, c.RequestTimeout, r.method, r.path)
+	}
+	return header, err
+}
+
+func (c *HTTPClient) send(ctx context.Context, r request, body []byte) (http.Header, error) {
+	resp, err := c.roundTrip(ctx, r, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if r.out != nil {
+		if err := c.decode(resp.Body, c.responseCodec(resp.Header), r.out); err != nil {
+			return nil, err
+		}
+	}
+	return resp.Header, nil
+}
+
+// responseCodec returns the Codec to decode a response with header: c's
+// Codec unless the response is labelled as JSON of some other media type.
+func (c *HTTPClient) responseCodec(header http.Header) Codec {
+	codec := c.codec()
+	ct := header.Get("Content-Type")
+	if !sameMediaType(ct, codec.ContentType()) && sameMediaType(ct, JSONCodec{}.ContentType()) {
+		return JSONCodec{}
+	}
+	return codec
+}
+
+// decode reads body, up to c.MaxResponseBytes, and decodes it into v with
+// codec. An empty body leaves v as it is.
+func (c *HTTPClient) decode(body io.Reader, codec Codec, v interface{}) error {
+	if c.MaxResponseBytes > 0 {
+		body = io.LimitReader(body, c.MaxResponseBytes+1)
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if c.MaxResponseBytes > 0 && int64(len(b)) > c.MaxResponseBytes {
+		return fmt.Errorf("%w: over %d bytes", ErrResponseTooLarge, c.MaxResponseBytes)
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil
+	}
+	return codec.Unmarshal(b, v)
+}
+
+// roundTrip sends a single attempt at r and returns the response, whose body
+// the caller must close, or an *HTTPError for a non-2xx status.
+func (c *HTTPClient) roundTrip(ctx context.Context, r request, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	tenant := TenantFromContext(ctx)
+	if tenant == "" && !c.NoTenant {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoTenant, r.method, r.path)
+	}
+
+	u := c.BaseURL + r.path
+	if len(r.query) > 0 {
+		u += "?" + r.query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	// Copied, as the client's Header is shared by concurrent requests.
+	for k, v := range c.Header {
+		req.Header[k] = append([]string{}, v...)
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", c.codec().ContentType())
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("X-Request-ID", c.requestID(ctx))
+	if tenant != "" {
+		req.Header.Set("X-Tenant", tenant)
+	}
+	if body != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", c.codec().ContentType())
+	}
+	if c.Auth != nil {
+		if err := c.Auth(req); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.limiter != nil {
+		if err := c.limiter.wait(ctx, c.Clock); err != nil {
+			return nil, err
+		}
+	}
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clock := clockOr(c.Clock)
+	start := clock.Now()
+	resp, err := c.httpClient().Do(req)
+	if c.Logger != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		c.Logger(r.method, u, status, clock.Now().Sub(start))
+	}
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+	if err := c.decompress(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, c.maxErrorBytes()))
+		he := &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status, Body: b, URL: u}
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			he.RetryAfter = retryAfter(resp.Header.Get("Retry-After"), clock.Now())
+		}
+		return nil, he
+	}
+	return resp, nil
+}
+
+// maxErrorBodyBytes caps how much of an error response HTTPError keeps.
+const maxErrorBodyBytes = 64 << 10
+
+// maxErrorBytes is how much of an error response to read: maxErrorBodyBytes,
+// or MaxResponseBytes when that is smaller.
+func (c *HTTPClient) maxErrorBytes() int64 {
+	if c.MaxResponseBytes > 0 && c.MaxResponseBytes < maxErrorBodyBytes {
+		return c.MaxResponseBytes
+	}
+	return maxErrorBodyBytes
+}
+
+// retryAfter parses a Retry-After value, either a number of seconds or an
+// HTTP date, into the wait from now. It returns zero for a missing or
+// malformed value, or a date already past.
+func retryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return max(time.Duration(secs)*time.Second, 0)
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0)
+	}
+	return 0
+}
+
+// decompress replaces a gzipped response body with its decompressed form,
+// and notes whether the backend understands gzip.
+func (c *HTTPClient) decompress(resp *http.Response) error {
+	gzipped := strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip")
+	if gzipped || strings.Contains(strings.ToLower(resp.Header.Get("Accept-Encoding")), "gzip") {
+		c.acceptsGzip.Store(true)
+	}
+	if !gzipped {
+		return nil
+	}
+
+	zr, err := gzip.NewReader(resp.Body)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body = &gzipBody{zr, resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	return nil
+}
+
+// acquire takes one of c.MaxConcurrency slots for a request, waiting within
+// ctx for one to free up, and returns the function that gives it back.
+func (c *HTTPClient) acquire(ctx context.Context) (release func(), err error) {
+	if c.MaxConcurrency <= 0 {
+		return func() {}, nil
+	}
+	c.slotsOnce.Do(func() {
+		c.slots = make(chan struct{}, c.MaxConcurrency)
+	})
+	select {
+	case c.slots <- struct{}{}:
+		return func() { <-c.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// releaseBody gives back a request's concurrency slot when the response
+// body is closed.
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// gzipBody reads a gzipped response body and closes the underlying body.
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b *gzipBody) Close() error {
+	b.Reader.Close()
+	return b.body.Close()
+}
+
+func gzipBytes(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,1511 @@
+package grocery
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPClientCreate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Error("expected POST but was", r.Method)
+		}
+		if r.URL.Path != "/notes" {
+			t.Error("expected /notes but was", r.URL.Path)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Error("expected json content type but was", ct)
+		}
+		var n Note
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Fatal(err)
+		}
+		if !n.Equal(&Note{Text: "apples", Quantity: 2}) {
+			t.Errorf("expected 2 apples but was %+v", n)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"42","text":"apples","quantity":2}`))
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	n := &Note{Text: "apples", Quantity: 2}
+	if err := client.Create(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if n.ID != "42" {
+		t.Fatal("expected the server-assigned ID 42 but was", n.ID)
+	}
+}
+
+func TestHTTPClientCreateMany(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Error("expected POST but was", r.Method)
+		}
+		if r.URL.Path != "/notes/batch" {
+			t.Error("expected /notes/batch but was", r.URL.Path)
+		}
+		var notes []Note
+		if err := json.NewDecoder(r.Body).Decode(&notes); err != nil {
+			t.Fatal(err)
+		}
+		if len(notes) != 2 || notes[0].Text != "apples" || notes[1].Text != "milk" {
+			t.Errorf("expected apples and milk but was %+v", notes)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"notes":[{"id":"1","text":"apples"},{"id":"2","text":"milk"}]}`))
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	notes := []*Note{{Text: "apples"}, {Text: "milk"}}
+	if err := client.CreateMany(context.Background(), notes); err != nil {
+		t.Fatal(err)
+	}
+	if notes[0].ID != "1" || notes[1].ID != "2" {
+		t.Errorf("expected the server-assigned IDs 1 and 2 but were %q and %q", notes[0].ID, notes[1].ID)
+	}
+}
+
+func TestHTTPClientCreateManyPartialFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"notes":[{"id":"1","text":"apples"}],"errors":[{"index":1,"message":"text too long"}]}`))
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	notes := []*Note{{Text: "apples"}, {Text: "milk"}}
+	err := client.CreateMany(context.Background(), notes)
+	if notes[0].ID != "1" || notes[1].ID != "" {
+		t.Errorf("expected only apples to get an ID but were %q and %q", notes[0].ID, notes[1].ID)
+	}
+	batchErr, ok := err.(*BatchError)
+	if !ok {
+		t.Fatalf("expected a *BatchError but was %v", err)
+	}
+	if len(batchErr.Failures) != 1 || batchErr.Failures[0].Index != 1 {
+		t.Fatalf("expected index 1 to fail but was %+v", batchErr.Failures)
+	}
+	if !strings.Contains(err.Error(), "index 1: text too long") {
+		t.Error("expected error to describe the failed index but was", err)
+	}
+}
+
+func TestHTTPClientReplaceAll(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/notes" {
+			t.Errorf("expected PUT /notes but was %s %s", r.Method, r.URL.Path)
+		}
+		var notes []*Note
+		if err := json.NewDecoder(r.Body).Decode(&notes); err != nil {
+			t.Error(err)
+		}
+		if notes == nil {
+			t.Error("expected an array, even an empty one")
+		}
+		for i, n := range notes {
+			n.ID = strconv.Itoa(i + 1)
+		}
+		json.NewEncoder(w).Encode(notes)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, WithoutTenant())
+	notes := []*Note{{Text: "apples", Quantity: 1}, {Text: "milk", Quantity: 1}}
+	if err := client.ReplaceAll(context.Background(), notes); err != nil {
+		t.Fatal(err)
+	}
+	if notes[0].ID != "1" || notes[1].ID != "2" {
+		t.Errorf("expected the IDs the backend gave but was %s", formatNotes(notes))
+	}
+	if err := client.ReplaceAll(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPClientReplaceAllMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id":"1","text":"apples"}]`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, WithoutTenant())
+	notes := []*Note{{Text: "apples", Quantity: 1}, {Text: "milk", Quantity: 1}}
+	if err := client.ReplaceAll(context.Background(), notes); err == nil {
+		t.Error("expected an error when the backend stores a different number of notes")
+	}
+}
+
+func TestHTTPClientAll(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Error("expected GET but was", r.Method)
+		}
+		if r.URL.Path != "/notes" {
+			t.Error("expected /notes but was", r.URL.Path)
+		}
+		w.Write([]byte(`[{"text":"apples","quantity":3},{"text":"milk","quantity":1,"category":"dairy"}]`))
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	notes, err := client.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 2 {
+		t.Fatal("expected two notes but was", len(notes))
+	}
+	if !notes[0].Equal(&Note{Text: "apples", Quantity: 3}) {
+		t.Errorf("expected 3 apples but was %+v", notes[0])
+	}
+	if !notes[1].Equal(&Note{Text: "milk", Quantity: 1, Category: "dairy"}) {
+		t.Errorf("expected 1 milk but was %+v", notes[1])
+	}
+}
+
+func TestHTTPClientTimestamps(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id":"1","text":"apples","created_at":"2024-03-01T10:00:00Z","updated_at":"2024-03-02T11:30:00Z"}]`))
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	notes, err := client.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 {
+		t.Fatal("expected 1 note but was", len(notes))
+	}
+	if want := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC); !notes[0].CreatedAt.Equal(want) {
+		t.Error("expected created at", want, "but was", notes[0].CreatedAt)
+	}
+	if want := time.Date(2024, 3, 2, 11, 30, 0, 0, time.UTC); !notes[0].UpdatedAt.Equal(want) {
+		t.Error("expected updated at", want, "but was", notes[0].UpdatedAt)
+	}
+}
+
+func TestHTTPClientOrderAndUnit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n Note
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Error(err)
+		}
+		if n.Order != 3 {
+			t.Error("expected order 3 but was", n.Order)
+		}
+		if n.Unit != "lb" {
+			t.Error("expected unit lb but was", n.Unit)
+		}
+		json.NewEncoder(w).Encode(n)
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	if err := client.Update(context.Background(), &Note{ID: "1", Text: "apples", Unit: "lb", Order: 3}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPClientMeta(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n Note
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Error(err)
+		}
+		if n.Meta["brand"] != "Acme" {
+			t.Errorf("expected brand Acme but was %v", n.Meta)
+		}
+		n.Meta["aisle"] = "4"
+		json.NewEncoder(w).Encode(n)
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	n := &Note{ID: "1", Text: "apples", Meta: map[string]string{"brand": "Acme"}}
+	if err := client.Update(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if n.Meta["aisle"] != "4" || n.Meta["brand"] != "Acme" {
+		t.Errorf("expected the meta sent back but was %v", n.Meta)
+	}
+}
+
+func TestHTTPClientAllPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if q := r.URL.Query(); q.Get("limit") != "2" || q.Get("offset") != "4" {
+			t.Error("expected limit 2 offset 4 but was", r.URL.RawQuery)
+		}
+		w.Header().Set("X-Total-Count", "5")
+		w.Write([]byte(`[{"text":"eggs"}]`))
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	notes, total, err := client.AllPage(context.Background(), 2, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 5 {
+		t.Fatal("expected a total of 5 but was", total)
+	}
+	if len(notes) != 1 || notes[0].Text != "eggs" {
+		t.Fatalf("expected eggs but was %+v", notes)
+	}
+}
+
+func TestHTTPClientAllNotModified(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if requests > 1 {
+			t.Error("expected If-None-Match on request", requests, "but was", r.Header.Get("If-None-Match"))
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`[{"id":"1","text":"apples"},{"id":"2","text":"milk"}]`))
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	for i := 0; i < 2; i++ {
+		notes, err := client.All(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(notes) != 2 || notes[0].Text != "apples" || notes[1].Text != "milk" {
+			t.Fatal("expected apples and milk but was", formatNotes(notes))
+		}
+		notes[0].Text = "changed"
+	}
+	if requests != 2 {
+		t.Fatal("expected 2 requests but was", requests)
+	}
+}
+
+func TestHTTPClientLimitsErrorBodies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(strings.Repeat("a", 1<<20)))
+	}))
+	defer server.Close()
+
+	for _, limit := range []int64{1024, 0} {
+		client := NewHTTPClient(server.URL, WithoutTenant(), WithMaxResponseBytes(limit))
+		_, err := client.All(context.Background())
+		var he *HTTPError
+		if !errors.As(err, &he) {
+			t.Fatal("expected an HTTPError but was", err)
+		}
+		want := limit
+		if want == 0 {
+			want = maxErrorBodyBytes
+		}
+		if int64(len(he.Body)) != want {
+			t.Errorf("limit %d: expected the body cut at %d bytes but was %d", limit, want, len(he.Body))
+		}
+	}
+}
+
+func TestHTTPClientMaxResponseBytes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"text":"` + strings.Repeat("a", 2000) + `"}]`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithMaxResponseBytes(1024))
+	if _, err := client.All(context.Background()); !errors.Is(err, ErrResponseTooLarge) {
+		t.Fatal("expected ErrResponseTooLarge but was", err)
+	}
+
+	client = NewHTTPClient(server.URL, WithoutTenant(), WithMaxResponseBytes(0))
+	notes, err := client.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 || len(notes[0].Text) != 2000 {
+		t.Errorf("expected the whole note without a limit but was %d notes", len(notes))
+	}
+	if NewHTTPClient(server.URL, WithoutTenant()).MaxResponseBytes != DefaultMaxResponseBytes {
+		t.Error("expected the default limit")
+	}
+}
+
+func TestHTTPClientAllFetchesEveryPage(t *testing.T) {
+	all := []Note{{Text: "apples"}, {Text: "milk"}, {Text: "bread"}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		end := offset + limit
+		if end > len(all) {
+			end = len(all)
+		}
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(all)))
+		json.NewEncoder(w).Encode(all[offset:end])
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithPageSize(2))
+	notes, err := client.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 3 || notes[2].Text != "bread" {
+		t.Fatalf("expected all three notes but was %+v", notes)
+	}
+}
+
+func TestHTTPClientGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch text := r.URL.Query().Get("text"); text {
+		case "apples":
+			w.Write([]byte(`[{"id":"1","text":"apples","quantity":3}]`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	n, err := client.Get(context.Background(), "apples")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.ID != "1" || n.Quantity != 3 {
+		t.Fatalf("expected note 1 with 3 apples but was %+v", n)
+	}
+	if n, err := client.Get(context.Background(), "bread"); err != ErrItemNotFound || n != nil {
+		t.Fatalf("expected ErrItemNotFound but was %v and %+v", err, n)
+	}
+}
+
+func TestHTTPClientGetMany(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if texts := r.URL.Query()["text"]; strings.Join(texts, ",") != "apples,bread,milk" {
+			t.Error("expected apples, bread and milk but was", texts)
+		}
+		w.Write([]byte(`[{"id":"1","text":"apples","quantity":3},{"id":"2","text":"milk","quantity":1}]`))
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	notes, err := client.GetMany(context.Background(), []string{"apples", "bread", "milk"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 2 || notes["apples"].ID != "1" || notes["milk"].ID != "2" {
+		t.Fatalf("expected apples and milk but was %v", notes)
+	}
+}
+
+func TestHTTPClientAllSorted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("sort") != "text" || q.Get("order") != "asc" {
+			t.Error("expected sort=text&order=asc but was", r.URL.RawQuery)
+		}
+		w.Write([]byte(`[{"text":"Bread"},{"text":"apples"},{"text":"milk","archived":true}]`))
+	}))
+	defer server.Close()
+
+	list := New()
+	list.Store = &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	items, err := list.ItemsSorted(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(items, ",") != "Bread,apples" {
+		t.Error("expected the backend's order without archived items but was", items)
+	}
+
+	if _, err := list.Store.(Sorter).AllSorted(context.Background(), "price", true); !errors.Is(err, ErrUnknownSortField) {
+		t.Error("expected ErrUnknownSortField but was", err)
+	}
+}
+
+func TestHTTPClientSearch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if q := r.URL.Query().Get("q"); q != "apple" {
+			t.Error("expected q=apple but was", q)
+		}
+		w.Write([]byte(`[{"text":"apples"}]`))
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	notes, err := client.Search(context.Background(), "apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 || notes[0].Text != "apples" {
+		t.Fatalf("expected apples but was %+v", notes)
+	}
+}
+
+func TestHTTPClientUpdate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Error("expected PUT but was", r.Method)
+		}
+		if r.URL.Path != "/notes/7" {
+			t.Error("expected /notes/7 but was", r.URL.Path)
+		}
+		var n Note
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Fatal(err)
+		}
+		if !n.Purchased {
+			t.Errorf("expected a purchased note but was %+v", n)
+		}
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	if err := client.Update(context.Background(), &Note{ID: "7", Text: "apples", Purchased: true}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPClientUpdateConflict(t *testing.T) {
+	version := 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`[{"id":"1","text":"apples","version":1}]`))
+			return
+		}
+		if match := r.Header.Get("If-Match"); match != strconv.Quote(strconv.Itoa(version)) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		version++
+		fmt.Fprintf(w, `{"id":"1","text":"green apples","version":%d}`, version)
+	}))
+	defer server.Close()
+
+	list := New()
+	list.Store = &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	if err := list.UpdateItem(context.Background(), "apples", "green apples"); !errors.Is(err, ErrConflict) {
+		t.Fatal("expected ErrConflict but was", err)
+	}
+
+	n := &Note{ID: "1", Text: "green apples", Version: 2}
+	if err := list.Store.Update(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if n.Version != 3 {
+		t.Error("expected version 3 from the backend but was", n.Version)
+	}
+}
+
+func TestHTTPClientUpdateFields(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Error("expected PATCH but was", r.Method)
+		}
+		if r.URL.Path != "/notes/7" {
+			t.Error("expected /notes/7 but was", r.URL.Path)
+		}
+		var fields map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+			t.Error(err)
+		}
+		if len(fields) != 1 || fields["quantity"] != 3.0 {
+			t.Error("expected only quantity 3 but was", fields)
+		}
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	if err := client.UpdateFields(context.Background(), "7", map[string]interface{}{"quantity": 3}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPClientArchived(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch {
+			var fields map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+				t.Error(err)
+			}
+			if fields["archived"] != true {
+				t.Error("expected archived to be set but was", fields)
+			}
+			return
+		}
+		w.Write([]byte(`[{"id":"1","text":"milk","quantity":1,"archived":true},{"id":"2","text":"apples","quantity":3}]`))
+	}))
+	defer server.Close()
+
+	list := New()
+	list.Store = &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	items, err := list.ArchivedItems(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0] != "milk" {
+		t.Errorf("expected milk to be archived but was %q", items)
+	}
+	if err := list.ArchiveItem(context.Background(), "apples"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPClientCount(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/notes/count" {
+			t.Error("expected /notes/count but was", r.URL.Path)
+		}
+		w.Write([]byte(`{"count":12}`))
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	n, err := client.Count(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 12 {
+		t.Fatal("expected 12 but was", n)
+	}
+}
+
+func TestHTTPClientCategories(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/notes/categories" {
+			t.Error("expected /notes/categories but was", r.URL.Path)
+		}
+		w.Write([]byte(`["produce","","dairy","produce"]`))
+	}))
+	defer server.Close()
+
+	list := New()
+	list.Store = NewHTTPClient(server.URL, WithoutTenant())
+	categories, err := list.Categories(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"dairy", "produce", Uncategorized}; !reflect.DeepEqual(categories, want) {
+		t.Errorf("expected %q but was %q", want, categories)
+	}
+}
+
+func TestHTTPClientCategoriesWithoutEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/notes/categories" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[{"text":"apples","category":"produce"},{"text":"tape"}]`))
+	}))
+	defer server.Close()
+
+	var logged []string
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithLogf(func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}))
+	categories, err := client.Categories(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"produce", ""}; !reflect.DeepEqual(categories, want) {
+		t.Errorf("expected %q but was %q", want, categories)
+	}
+	if len(logged) != 1 || !strings.Contains(logged[0], "falling back to listing notes") {
+		t.Errorf("expected the fallback to be logged but was %q", logged)
+	}
+}
+
+func TestHTTPClientRedirects(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old/notes":
+			http.Redirect(w, r, server.URL+"/notes", http.StatusMovedPermanently)
+		case "/older/notes":
+			http.Redirect(w, r, server.URL+"/old/notes", http.StatusMovedPermanently)
+		default:
+			w.Write([]byte(`[{"text":"apples"}]`))
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	client := NewHTTPClient(server.URL+"/old", WithoutTenant())
+	if notes, err := client.All(ctx); err != nil || len(notes) != 1 {
+		t.Fatalf("expected the redirect followed but was %d notes, %v", len(notes), err)
+	}
+	if notes, err := (&HTTPClient{BaseURL: server.URL + "/older", NoTenant: true}).All(ctx); err != nil || len(notes) != 1 {
+		t.Fatalf("expected the zero HTTPClient to follow redirects but was %d notes, %v", len(notes), err)
+	}
+
+	client = NewHTTPClient(server.URL+"/old", WithoutTenant(), WithRedirects(0), WithRetryPolicy(RetryPolicy{MaxRetries: 2}))
+	_, err := client.All(ctx)
+	if !errors.Is(err, ErrRedirectRefused) {
+		t.Fatal("expected ErrRedirectRefused but was", err)
+	}
+	if !strings.Contains(err.Error(), server.URL+"/notes") {
+		t.Error("expected the error to give the target but was", err)
+	}
+
+	client = NewHTTPClient(server.URL+"/older", WithoutTenant(), WithRedirects(1))
+	if _, err := client.All(ctx); !errors.Is(err, ErrRedirectRefused) {
+		t.Fatal("expected ErrRedirectRefused past one redirect but was", err)
+	}
+	client = NewHTTPClient(server.URL+"/older", WithoutTenant(), WithRedirects(2))
+	if _, err := client.All(ctx); err != nil {
+		t.Fatal("expected two redirects followed but was", err)
+	}
+}
+
+func TestHTTPClientPing(t *testing.T) {
+	healthy := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			t.Error("expected /health but was", r.URL.Path)
+		}
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true, Retry: RetryPolicy{MaxRetries: 3}}
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	healthy = false
+	var he *HTTPError
+	if err := client.Ping(context.Background()); !errors.As(err, &he) || he.StatusCode != http.StatusServiceUnavailable {
+		t.Fatal("expected a 503 but was", err)
+	}
+}
+
+func TestHTTPClientPingServerDown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	client := &HTTPClient{BaseURL: url, NoTenant: true, Retry: RetryPolicy{MaxRetries: 5, BaseDelay: time.Second}}
+	start := time.Now()
+	if err := client.Ping(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Fatal("expected Ping to fail fast but took", d)
+	}
+}
+
+func TestHTTPClientGzipResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			t.Error("expected Accept-Encoding gzip but was", r.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		zw.Write([]byte(`[{"text":"apples"},{"text":"milk"}]`))
+		zw.Close()
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	notes, err := client.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 2 || notes[0].Text != "apples" || notes[1].Text != "milk" {
+		t.Fatal("expected apples and milk but was", formatNotes(notes))
+	}
+}
+
+func TestHTTPClientGzipRequest(t *testing.T) {
+	var encodings []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Accept-Encoding", "gzip")
+		if r.Method != http.MethodPost {
+			return
+		}
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		body := io.Reader(r.Body)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			body = zr
+		}
+		var notes []*Note
+		if err := json.NewDecoder(body).Decode(&notes); err != nil {
+			t.Error(err)
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithCompression(100))
+	big := make([]*Note, 20)
+	for i := range big {
+		big[i] = &Note{Text: fmt.Sprint("item ", i)}
+	}
+
+	// The backend hasn't said it accepts gzip yet.
+	if err := client.CreateMany(context.Background(), big); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.CreateMany(context.Background(), big); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.CreateMany(context.Background(), []*Note{{Text: "apples"}}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"", "gzip", ""}; strings.Join(encodings, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected encodings %q but were %q", want, encodings)
+	}
+}
+
+func TestHTTPClientGzipRequestUnsupported(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if enc := r.Header.Get("Content-Encoding"); enc != "" {
+			t.Error("expected no Content-Encoding but was", enc)
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithCompression(1))
+	for i := 0; i < 2; i++ {
+		if err := client.CreateMany(context.Background(), []*Note{{Text: "apples"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHTTPClientCountFallsBackToPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/notes/count" {
+			http.NotFound(w, r)
+			return
+		}
+		if limit := r.URL.Query().Get("limit"); limit != "1" {
+			t.Error("expected a one-note page but the limit was", limit)
+		}
+		w.Header().Set("X-Total-Count", "250")
+		w.Write([]byte(`[{"text":"apples"}]`))
+	}))
+	defer server.Close()
+
+	var logged []string
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true, Logf: func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}}
+	n, err := client.Count(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 250 {
+		t.Fatal("expected the page's total of 250 but was", n)
+	}
+	if len(logged) != 1 || !strings.Contains(logged[0], "no count endpoint (status 404), falling back to listing notes") {
+		t.Errorf("expected the fallback to be logged but was %q", logged)
+	}
+}
+
+func TestHTTPClientDelete(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Error("expected DELETE but was", r.Method)
+		}
+		if r.URL.Path != "/notes/a b" {
+			t.Error("expected /notes/a b but was", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	if err := client.Delete(context.Background(), &Note{ID: "a b", Text: "green apples"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPClientDeleteMany(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/notes/batch/delete" {
+			t.Error("expected POST /notes/batch/delete but was", r.Method, r.URL.Path)
+		}
+		var ids []string
+		if err := json.NewDecoder(r.Body).Decode(&ids); err != nil {
+			t.Error(err)
+		}
+		if strings.Join(ids, ",") != "1,2" {
+			t.Error("expected IDs 1 and 2 but was", ids)
+		}
+		w.Write([]byte(`{"errors":[{"index":1,"message":"locked"}]}`))
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	err := client.DeleteMany(context.Background(), []*Note{{ID: "1", Text: "apples"}, {ID: "2", Text: "milk"}})
+	var be *BatchError
+	if !errors.As(err, &be) || len(be.Failures) != 1 || be.Failures[0] != (BatchFailure{1, "locked"}) {
+		t.Fatal("expected milk to fail but was", err)
+	}
+}
+
+func TestHTTPClientDeleteWithoutID(t *testing.T) {
+	client := &HTTPClient{BaseURL: "http://example.invalid", NoTenant: true}
+	if err := client.Delete(context.Background(), &Note{Text: "apples"}); err == nil {
+		t.Fatal("expected an error deleting a note without an ID")
+	}
+}
+
+func TestHTTPClientErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "store is on fire", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	_, err := client.All(context.Background())
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(err.Error(), "500") {
+		t.Error("expected error to include status code but was", err)
+	}
+	if !strings.Contains(err.Error(), "store is on fire") {
+		t.Error("expected error to include body but was", err)
+	}
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("expected an *HTTPError but was %T", err)
+	}
+	if httpErr.StatusCode != http.StatusInternalServerError {
+		t.Error("expected status 500 but was", httpErr.StatusCode)
+	}
+	if httpErr.Status != "500 Internal Server Error" {
+		t.Error("expected status 500 Internal Server Error but was", httpErr.Status)
+	}
+	if want := server.URL + "/notes?limit=100&offset=0"; httpErr.URL != want {
+		t.Errorf("expected URL %s but was %s", want, httpErr.URL)
+	}
+	if string(httpErr.Body) != "store is on fire\n" {
+		t.Errorf("expected body %q but was %q", "store is on fire\n", httpErr.Body)
+	}
+}
+
+func TestHTTPClientCreateErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	err := client.Create(context.Background(), &Note{Text: "apples"})
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("expected an *HTTPError but was %T", err)
+	}
+	if httpErr.StatusCode != http.StatusTooManyRequests || httpErr.Status != "429 Too Many Requests" {
+		t.Error("expected status 429 Too Many Requests but was", httpErr.Status)
+	}
+	if httpErr.URL != server.URL+"/notes" {
+		t.Errorf("expected URL %s/notes but was %s", server.URL, httpErr.URL)
+	}
+}
+
+func TestHTTPClientNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	err := client.Delete(context.Background(), &Note{ID: "1", Text: "apples"})
+	if !errors.Is(err, ErrItemNotFound) {
+		t.Fatal("expected a 404 to be ErrItemNotFound but was", err)
+	}
+}
+
+type countingTransport struct {
+	requests int
+}
+
+func (rt *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	rt.requests++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestHTTPClientWithHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	rt := &countingTransport{}
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithHTTPClient(&http.Client{Transport: rt}))
+	if _, err := client.All(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if rt.requests != 1 {
+		t.Fatal("expected the injected client to send one request but was", rt.requests)
+	}
+}
+
+func TestHTTPClientBearerToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer s3cret" {
+			t.Error("expected bearer token on", r.Method, "but was", auth)
+		}
+		if r.Method == http.MethodPost {
+			w.Write([]byte(`{"id":"1","text":"apples"}`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithAuth(BearerToken("s3cret")))
+	if _, err := client.All(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Create(context.Background(), &Note{Text: "apples"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPClientAuthRefreshesToken(t *testing.T) {
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("Authorization"))
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	tokens := []string{"first", "second"}
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithAuth(func(r *http.Request) error {
+		token := tokens[0]
+		tokens = tokens[1:]
+		return BearerToken(token)(r)
+	}))
+	client.All(context.Background())
+	client.All(context.Background())
+
+	if len(seen) != 2 || seen[0] != "Bearer first" || seen[1] != "Bearer second" {
+		t.Fatal("expected each request to fetch a fresh token but was", seen)
+	}
+}
+
+func TestHTTPClientAuthError(t *testing.T) {
+	client := NewHTTPClient("http://example.invalid", WithoutTenant(), WithAuth(func(r *http.Request) error {
+		return errors.New("token expired")
+	}))
+	if _, err := client.All(context.Background()); err == nil || err.Error() != "token expired" {
+		t.Fatal("expected the auth error but was", err)
+	}
+}
+
+type logEntry struct {
+	method, url string
+	status      int
+}
+
+func TestHTTPClientLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			http.Error(w, "nope", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	var entries []logEntry
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithPageSize(10), WithLogger(func(method, url string, status int, dur time.Duration) {
+		if dur <= 0 {
+			t.Error("expected a positive latency but was", dur)
+		}
+		entries = append(entries, logEntry{method, url, status})
+	}))
+	client.All(context.Background())
+	client.Create(context.Background(), &Note{Text: "apples"})
+
+	want := []logEntry{
+		{http.MethodGet, server.URL + "/notes?limit=10&offset=0", http.StatusOK},
+		{http.MethodPost, server.URL + "/notes", http.StatusBadRequest},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d log entries but was %+v", len(want), entries)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("expected log entry %+v but was %+v", want[i], entries[i])
+		}
+	}
+}
+
+// envelopeCodec wraps every body in {"data": ...}.
+type envelopeCodec struct{}
+
+func (envelopeCodec) ContentType() string {
+	return "application/vnd.envelope+json"
+}
+
+func (envelopeCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{"data": v})
+}
+
+func (envelopeCodec) Unmarshal(data []byte, v interface{}) error {
+	var env struct{ Data json.RawMessage }
+	if err := json.Unmarshal(data, &env); err != nil {
+		return err
+	}
+	return json.Unmarshal(env.Data, v)
+}
+
+func TestHTTPClientCodec(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if accept := r.Header.Get("Accept"); accept != "application/vnd.envelope+json" {
+			t.Error("expected the codec's content type to be accepted but was", accept)
+		}
+		if r.Method == http.MethodPost {
+			var env struct{ Data Note }
+			if err := json.NewDecoder(r.Body).Decode(&env); err != nil {
+				t.Error(err)
+			}
+			if env.Data.Text != "apples" {
+				t.Errorf("expected enveloped apples but was %+v", env)
+			}
+			w.Write([]byte(`{"data":{"id":"7"}}`))
+			return
+		}
+		w.Write([]byte(`{"data":[{"id":"7","text":"apples","quantity":3}]}`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithCodec(envelopeCodec{}))
+	n := &Note{Text: "apples", Quantity: 3}
+	if err := client.Create(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if n.ID != "7" {
+		t.Errorf("expected id 7 from the envelope but was %q", n.ID)
+	}
+
+	notes, err := client.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 || notes[0].Quantity != 3 {
+		t.Fatalf("expected 3 apples but was %s", formatNotes(notes))
+	}
+
+	stream, errc := client.AllStream(context.Background())
+	streamed := 0
+	for range stream {
+		streamed++
+	}
+	if err := <-errc; err != nil || streamed != 1 {
+		t.Errorf("expected 1 streamed note but was %d and %v", streamed, err)
+	}
+}
+
+func TestHTTPClientFormContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != FormContentType {
+			t.Error("expected a form content type but was", ct)
+		}
+		b, _ := io.ReadAll(r.Body)
+		form, err := url.ParseQuery(string(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := url.Values{"text": {"apples"}, "quantity": {"2"}, "purchased": {"false"}, "category": {"produce"}, "meta[brand]": {"acme"}}
+		if !reflect.DeepEqual(form, want) {
+			t.Errorf("expected the form %v but was %v", want, form)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"42","text":"apples","quantity":2,"category":"produce"}`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithContentType(FormContentType))
+	n := &Note{Text: "apples", Quantity: 2, Category: "produce", Meta: map[string]string{"brand": "acme"}}
+	if err := client.Create(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if n.ID != "42" {
+		t.Error("expected the JSON response decoded with ID 42 but was", n.ID)
+	}
+
+	client.ContentType = "text/csv"
+	if err := client.Create(context.Background(), &Note{Text: "milk"}); err == nil {
+		t.Error("expected an error for an unsupported content type")
+	}
+}
+
+// jsonEnvelopeCodec is envelopeCodec labelled as plain JSON.
+type jsonEnvelopeCodec struct{ envelopeCodec }
+
+func (jsonEnvelopeCodec) ContentType() string {
+	return "application/json"
+}
+
+func TestHTTPClientResponseCodec(t *testing.T) {
+	body := `{"data":[{"id":"7","text":"apples","quantity":3}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithCodec(jsonEnvelopeCodec{}))
+	notes, err := client.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 || notes[0].Quantity != 3 {
+		t.Fatalf("expected 3 apples decoded with the codec but was %s", formatNotes(notes))
+	}
+	stream, errc := client.AllStream(context.Background())
+	streamed := 0
+	for range stream {
+		streamed++
+	}
+	if err := <-errc; err != nil || streamed != 1 {
+		t.Errorf("expected 1 note streamed with the codec but was %d and %v", streamed, err)
+	}
+
+	// A codec of another content type falls back to JSON for a JSON response.
+	body = `[{"id":"7","text":"apples","quantity":3}]`
+	client = NewHTTPClient(server.URL, WithoutTenant(), WithCodec(envelopeCodec{}))
+	notes, err = client.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 || notes[0].Quantity != 3 {
+		t.Fatalf("expected 3 apples decoded as JSON but was %s", formatNotes(notes))
+	}
+}
+
+func TestHTTPClientHeaders(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if ua := r.Header.Get("User-Agent"); ua != "grocery-client/1.2" {
+			t.Error("expected User-Agent grocery-client/1.2 but was", ua)
+		}
+		if tenant := r.Header.Get("X-Tenant-ID"); tenant != "acme" {
+			t.Error("expected tenant acme but was", tenant)
+		}
+		if versions := r.Header.Values("X-API-Version"); strings.Join(versions, ",") != "2,3" {
+			t.Error("expected API versions 2 and 3 but was", versions)
+		}
+		if accept := r.Header.Get("Accept"); accept != "application/json" {
+			t.Error("expected the client's own Accept to win but was", accept)
+		}
+		if r.Method == http.MethodPost {
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, WithoutTenant(),
+		WithUserAgent("grocery-client/1.2"),
+		WithHeader("X-Tenant-ID", "acme"),
+		WithHeader("X-API-Version", "2"),
+		WithHeader("X-API-Version", "3"),
+		WithHeader("Accept", "text/plain"))
+	if err := client.Create(context.Background(), &Note{Text: "apples"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.All(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Error("expected 2 requests but there were", requests)
+	}
+}
+
+func TestHTTPClientRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithRateLimit(20, 2))
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if _, err := client.All(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Two go at once, then one every 50ms.
+	if d := time.Since(start); d < 190*time.Millisecond {
+		t.Error("expected the calls to be spaced out over 200ms but took", d)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.All(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("expected waiting for the limit to stop with ctx but was", err)
+	}
+}
+
+func TestHTTPClientRateLimitUnlimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	for _, rate := range []float64{0, -1} {
+		client := NewHTTPClient(server.URL, WithoutTenant(), WithRateLimit(rate, 1))
+		start := time.Now()
+		for i := 0; i < 5; i++ {
+			if _, err := client.All(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("expected a rate of %v not to limit calls but they took %v", rate, d)
+		}
+	}
+}
+
+func TestHTTPClientMaxConcurrency(t *testing.T) {
+	var inflight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(5 * time.Millisecond)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithMaxConcurrency(3))
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.Create(context.Background(), &Note{Text: "apples"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if p := peak.Load(); p > 3 {
+		t.Error("expected at most 3 requests in flight but there were", p)
+	}
+}
+
+func TestHTTPClientMaxConcurrencyWaitsWithinContext(t *testing.T) {
+	arrived := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-release
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithMaxConcurrency(1))
+	done := make(chan error)
+	go func() {
+		_, err := client.All(context.Background())
+		done <- err
+	}()
+	<-arrived
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.All(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("expected waiting for a slot to stop with ctx but was", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPClientRequestID(t *testing.T) {
+	var ids []string
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get("X-Request-ID"))
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Method == http.MethodPost {
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithRetryPolicy(RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond}))
+	ctx := ContextWithRequestID(context.Background(), "trace-1")
+	if _, err := client.All(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Create(context.Background(), &Note{Text: "apples"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 3 || ids[0] != "trace-1" || ids[1] != "trace-1" {
+		t.Fatalf("expected trace-1 on both attempts at All but was %q", ids)
+	}
+	if len(ids[2]) != 36 {
+		t.Errorf("expected a generated UUID on Create but was %q", ids[2])
+	}
+
+	client.RequestID = func(ctx context.Context) string { return "from-func" }
+	if _, err := client.All(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids[len(ids)-1]; got != "from-func" {
+		t.Errorf("expected from-func but was %q", got)
+	}
+}
+
+func TestHTTPClientTenant(t *testing.T) {
+	var tenants []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenants = append(tenants, r.Header.Get("X-Tenant"))
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL)
+	list := New()
+	list.Store = client
+	if _, err := list.Items(ContextWithTenant(context.Background(), "acme")); err != nil {
+		t.Fatal(err)
+	}
+	if len(tenants) != 1 || tenants[0] != "acme" {
+		t.Fatalf("expected the acme tenant on the wire but was %q", tenants)
+	}
+
+	if err := list.AddItem(context.Background(), "apples"); !errors.Is(err, ErrNoTenant) {
+		t.Error("expected ErrNoTenant without a tenant but was", err)
+	}
+	if len(tenants) != 1 {
+		t.Error("expected no request without a tenant but there were", len(tenants)-1)
+	}
+
+	list.Store = NewHTTPClient(server.URL, WithoutTenant())
+	if _, err := list.Items(context.Background()); err != nil {
+		t.Fatal("expected WithoutTenant to allow the shared namespace but was", err)
+	}
+	if len(tenants) != 2 || tenants[1] != "" {
+		t.Errorf("expected a request with no tenant but was %q", tenants)
+	}
+}
+
+func TestHTTPClientAllStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher := w.(http.Flusher)
+		w.Write([]byte(`[`))
+		for i, text := range []string{"apples", "milk", "bread"} {
+			if i > 0 {
+				w.Write([]byte(`,`))
+			}
+			fmt.Fprintf(w, `{"text":%q}`, text)
+			flusher.Flush()
+		}
+		w.Write([]byte(`]`))
+	}))
+	defer server.Close()
+
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	notes, errc := client.AllStream(context.Background())
+
+	var texts []string
+	for n := range notes {
+		texts = append(texts, n.Text)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(texts, ",") != "apples,milk,bread" {
+		t.Fatal("expected apples, milk and bread but was", texts)
+	}
+}
+
+func TestHTTPClientAllStreamStopsWhenCancelled(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher := w.(http.Flusher)
+		w.Write([]byte(`[{"text":"apples"},`))
+		flusher.Flush()
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	notes, errc := client.AllStream(ctx)
+
+	if n := <-notes; n == nil || n.Text != "apples" {
+		t.Fatalf("expected apples first but was %+v", n)
+	}
+	cancel()
+	for range notes {
+	}
+	if err := <-errc; err == nil {
+		t.Fatal("expected the cancelled stream to report an error")
+	}
+}
+
+func TestGroceryListStreamItems(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"text":"apples"},{"text":"milk"}]`))
+	}))
+	defer server.Close()
+
+	list := New()
+	list.Store = &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	items, errc := list.StreamItems(context.Background())
+
+	var got []string
+	for item := range items {
+		got = append(got, item)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != "apples,milk" {
+		t.Fatal("expected apples and milk but was", got)
+	}
+}
@@ -0,0 +1,118 @@
+package grocery
+
+import (
+	"context"
+	"time"
+)
+
+// MetricsRecorder receives one observation per call made through an
+// InstrumentedStore, failed calls included.
+type MetricsRecorder interface {
+	ObserveCall(method string, dur time.Duration, err error)
+}
+
+// InstrumentedStore wraps an API and reports the latency and outcome of
+// every call to a MetricsRecorder, timed on Clock, the wall clock when nil.
+type InstrumentedStore struct {
+	Clock Clock
+
+	store API
+	rec   MetricsRecorder
+}
+
+func NewInstrumentedStore(store API, rec MetricsRecorder) *InstrumentedStore {
+	return &InstrumentedStore{store: store, rec: rec}
+}
+
+// observe reports a call to method that started at start.
+func (s *InstrumentedStore) observe(method string, start time.Time, err error) {
+	s.rec.ObserveCall(method, clockOr(s.Clock).Now().Sub(start), err)
+}
+
+func (s *InstrumentedStore) Create(ctx context.Context, n *Note) error {
+	start := clockOr(s.Clock).Now()
+	err := s.store.Create(ctx, n)
+	s.observe("Create", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) CreateMany(ctx context.Context, notes []*Note) error {
+	start := clockOr(s.Clock).Now()
+	err := s.store.CreateMany(ctx, notes)
+	s.observe("CreateMany", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) All(ctx context.Context) ([]*Note, error) {
+	start := clockOr(s.Clock).Now()
+	notes, err := s.store.All(ctx)
+	s.observe("All", start, err)
+	return notes, err
+}
+
+func (s *InstrumentedStore) Get(ctx context.Context, text string) (*Note, error) {
+	start := clockOr(s.Clock).Now()
+	n, err := s.store.Get(ctx, text)
+	s.observe("Get", start, err)
+	return n, err
+}
+
+func (s *InstrumentedStore) GetMany(ctx context.Context, texts []string) (map[string]*Note, error) {
+	start := clockOr(s.Clock).Now()
+	notes, err := s.store.GetMany(ctx, texts)
+	s.observe("GetMany", start, err)
+	return notes, err
+}
+
+func (s *InstrumentedStore) Count(ctx context.Context) (int, error) {
+	start := clockOr(s.Clock).Now()
+	n, err := countNotes(ctx, s.store)
+	s.observe("Count", start, err)
+	return n, err
+}
+
+func (s *InstrumentedStore) Update(ctx context.Context, n *Note) error {
+	start := clockOr(s.Clock).Now()
+	err := s.store.Update(ctx, n)
+	s.observe("Update", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) UpdateFields(ctx context.Context, id string, fields map[string]interface{}) error {
+	start := clockOr(s.Clock).Now()
+	err := s.store.UpdateFields(ctx, id, fields)
+	s.observe("UpdateFields", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) Delete(ctx context.Context, n *Note) error {
+	start := clockOr(s.Clock).Now()
+	err := s.store.Delete(ctx, n)
+	s.observe("Delete", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) DeleteMany(ctx context.Context, notes []*Note) error {
+	start := clockOr(s.Clock).Now()
+	err := s.store.DeleteMany(ctx, notes)
+	s.observe("DeleteMany", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) ReplaceAll(ctx context.Context, notes []*Note) error {
+	start := clockOr(s.Clock).Now()
+	err := s.store.ReplaceAll(ctx, notes)
+	s.observe("ReplaceAll", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) Ping(ctx context.Context) error {
+	start := clockOr(s.Clock).Now()
+	err := s.store.Ping(ctx)
+	s.observe("Ping", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) Close() error {
+	return closeStore(s.store)
+}
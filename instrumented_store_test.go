@@ -0,0 +1,53 @@
+package grocery
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type observation struct {
+	method string
+	err    error
+}
+
+type fakeRecorder struct {
+	observations []observation
+}
+
+func (r *fakeRecorder) ObserveCall(method string, dur time.Duration, err error) {
+	r.observations = append(r.observations, observation{method, err})
+}
+
+func TestInstrumentedStore(t *testing.T) {
+	client := NewFakeClient(t)
+	rec := &fakeRecorder{}
+	list := New()
+	list.Store = NewInstrumentedStore(client, rec)
+
+	failed := errors.New("store is on fire")
+	go func() {
+		client.AssertAll(nil, nil)
+		client.AssertCreate(&Note{Text: "apples", Quantity: 1}, nil)
+		client.AssertAll(nil, failed)
+		client.Close()
+	}()
+	if err := list.AddItem(context.Background(), "apples"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := list.Items(context.Background()); !errors.Is(err, failed) {
+		t.Fatal("expected store is on fire but was", err)
+	}
+	client.AssertDone()
+
+	want := []observation{{"All", nil}, {"Create", nil}, {"All", failed}}
+	if len(rec.observations) != len(want) {
+		t.Fatalf("expected %d observations but was %v", len(want), rec.observations)
+	}
+	for i, o := range rec.observations {
+		if o != want[i] {
+			t.Errorf("expected observation %d to be %v but was %v", i, want[i], o)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package grocery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+)
+
+// ExportJSON writes every note to w as a JSON array, in the same form the
+// HTTP API uses.
+func (g *GroceryList) ExportJSON(ctx context.Context, w io.Writer) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	notes, err := g.Store.All(ctx)
+	if err != nil {
+		return wrap("exporting items", err)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(notes)
+}
+
+// ImportJSON adds the notes in the JSON array read from r, as ExportJSON
+// writes it, in one CreateMany call and returns how many were imported and
+// how many were skipped as duplicates, as ImportCSV does. Ids, order and
+// timestamps are left to the store, and a missing quantity defaults to 1.
+//
+// Nothing is imported if the document is malformed or any note is invalid.
+func (g *GroceryList) ImportJSON(ctx context.Context, r io.Reader) (imported, skipped int, err error) {
+	var doc []*Note
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return 0, 0, wrap("importing items", err)
+	}
+
+	notes := make([]*Note, 0, len(doc))
+	for i, n := range doc {
+		if n == nil {
+			continue
+		}
+		text, err := g.validate(n.Text)
+		if err != nil {
+			return 0, 0, fmt.Errorf("grocery: importing items: note %d: %w", i, err)
+		}
+		quantity := n.Quantity
+		if quantity == 0 {
+			quantity = 1
+		}
+		note := &Note{
+			Text:      text,
+			Quantity:  quantity,
+			Unit:      n.Unit,
+			Purchased: n.Purchased,
+			Category:  n.Category,
+			Archived:  n.Archived,
+			Meta:      maps.Clone(n.Meta),
+		}
+		if err := note.Validate(); err != nil {
+			return 0, 0, fmt.Errorf("grocery: importing items: note %d: %w", i, err)
+		}
+		notes = append(notes, note)
+	}
+	return g.importNotes(ctx, notes)
+}
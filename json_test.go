@@ -0,0 +1,95 @@
+package grocery
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGroceryListJSONRoundTrip(t *testing.T) {
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	from := New()
+	from.Store = NewMemoryStore(
+		&Note{Text: "apples", Quantity: 3, Unit: "lb", Category: "produce", Meta: map[string]string{"brand": "acme"}},
+		&Note{Text: "milk", Quantity: 1, Purchased: true, CreatedAt: created},
+		&Note{Text: "tape", Quantity: 1, Archived: true},
+	)
+	var b bytes.Buffer
+	if err := from.ExportJSON(context.Background(), &b); err != nil {
+		t.Fatal(err)
+	}
+
+	to := New()
+	to.Store = NewMemoryStore(&Note{Text: "milk", Quantity: 1})
+	imported, skipped, err := to.ImportJSON(context.Background(), &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 2 || skipped != 1 {
+		t.Errorf("expected 2 imported and 1 skipped but was %d and %d", imported, skipped)
+	}
+	notes, err := to.Store.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Note{
+		{Text: "milk", Quantity: 1},
+		{Text: "apples", Quantity: 3, Unit: "lb", Category: "produce", Meta: map[string]string{"brand": "acme"}},
+		{Text: "tape", Quantity: 1, Archived: true},
+	}
+	if len(notes) != len(want) {
+		t.Fatalf("expected %d notes but was %s", len(want), formatNotes(notes))
+	}
+	for i, n := range notes {
+		n.ID = ""
+		if !n.Equal(want[i]) {
+			t.Errorf("expected %+v but was %+v", *want[i], *n)
+		}
+	}
+}
+
+func TestGroceryListExportJSON(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{{ID: "1", Text: "apples", Quantity: 3, Category: "produce"}}, nil)
+		client.Close()
+	}()
+	var b strings.Builder
+	if err := list.ExportJSON(context.Background(), &b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `[
+  {
+    "id": "1",
+    "text": "apples",
+    "quantity": 3,
+    "purchased": false,
+    "category": "produce"
+  }
+]
+`
+	if b.String() != want {
+		t.Fatalf("expected\n%s\nbut was\n%s", want, b.String())
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListImportJSONInvalid(t *testing.T) {
+	list := New()
+	list.Store = NewFakeClient(t)
+
+	_, _, err := list.ImportJSON(context.Background(), strings.NewReader(`[{"text":"apples"},{"text":" "}]`))
+	if !errors.Is(err, ErrEmptyItem) || !strings.Contains(err.Error(), "note 1") {
+		t.Error("expected ErrEmptyItem for note 1 but was", err)
+	}
+	if _, _, err := list.ImportJSON(context.Background(), strings.NewReader(`{"text":`)); err == nil {
+		t.Error("expected malformed JSON to fail")
+	}
+}
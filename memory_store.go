@@ -0,0 +1,288 @@
+package grocery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strconv"
+	"sync"
+)
+
+// MemoryStore is an API that keeps notes in memory, for examples, local
+// runs and tests. Notes are copied in and out so callers can't modify the
+// stored notes. The zero value is an empty store ready to use.
+//
+// Created notes get their IDs from IDGen, or when it is nil are random
+// UUIDs. Sequential opts in to a counter instead, "1", "2" and so on, for
+// tests that want predictable IDs. IDs already in the store are skipped, and
+// Create fails if IDGen keeps returning them.
+type MemoryStore struct {
+	IDGen      func() string
+	Sequential bool
+
+	mu     sync.Mutex
+	notes  []*Note
+	lastID int
+}
+
+// NewMemoryStore returns a store holding copies of notes, each given a new
+// ID; notes themselves are left as they are.
+func NewMemoryStore(notes ...*Note) *MemoryStore {
+	s := &MemoryStore{}
+	for _, n := range notes {
+		// UUIDs can't run out of unused IDs.
+		s.create(cloneNote(n))
+	}
+	return s
+}
+
+// Create stores a copy of n and sets n.ID to the ID it was stored under.
+func (s *MemoryStore) Create(ctx context.Context, n *Note) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.create(n)
+}
+
+func (s *MemoryStore) CreateMany(ctx context.Context, notes []*Note) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, n := range notes {
+		if err := s.create(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// idAttempts is how many IDs create asks IDGen for before giving up.
+const idAttempts = 10
+
+func (s *MemoryStore) create(n *Note) error {
+	for range idAttempts {
+		id := s.nextID()
+		if s.index(id) < 0 {
+			n.ID = id
+			s.notes = append(s.notes, cloneNote(n))
+			return nil
+		}
+	}
+	return fmt.Errorf("grocery: no unused ID in %d tries", idAttempts)
+}
+
+func (s *MemoryStore) nextID() string {
+	if s.IDGen != nil {
+		return s.IDGen()
+	}
+	if !s.Sequential {
+		return newUUID()
+	}
+	s.lastID++
+	return strconv.Itoa(s.lastID)
+}
+
+func (s *MemoryStore) All(ctx context.Context) ([]*Note, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return cloneNotes(s.notes), nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, text string) (*Note, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, n := range s.notes {
+		if n.Text == text {
+			return cloneNote(n), nil
+		}
+	}
+	return nil, ErrItemNotFound
+}
+
+func (s *MemoryStore) GetMany(ctx context.Context, texts []string) (map[string]*Note, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wanted := map[string]bool{}
+	for _, text := range texts {
+		wanted[text] = true
+	}
+	found := map[string]*Note{}
+	for _, n := range s.notes {
+		if _, ok := found[n.Text]; wanted[n.Text] && !ok {
+			found[n.Text] = cloneNote(n)
+		}
+	}
+	return found, nil
+}
+
+func (s *MemoryStore) AllPage(ctx context.Context, limit, offset int) ([]*Note, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start, end := pageBounds(len(s.notes), limit, offset)
+	return cloneNotes(s.notes[start:end]), len(s.notes), nil
+}
+
+// pageBounds returns the slice bounds of the page of up to limit of total
+// notes starting at offset. A negative limit or offset counts as zero.
+func pageBounds(total, limit, offset int) (start, end int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if limit < 0 {
+		limit = 0
+	}
+	if offset > total {
+		offset = total
+	}
+	end = offset + limit
+	if end > total {
+		end = total
+	}
+	return offset, end
+}
+
+func (s *MemoryStore) Count(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.notes), nil
+}
+
+func (s *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Update replaces the note with n's ID, returning ErrItemNotFound if there
+// is no such note.
+func (s *MemoryStore) Update(ctx context.Context, n *Note) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(n.ID)
+	if i < 0 {
+		return ErrItemNotFound
+	}
+	s.notes[i] = cloneNote(n)
+	return nil
+}
+
+// UpdateFields sets fields of the note with ID id, returning ErrItemNotFound
+// if there is no such note. Fields that Note doesn't have are an error.
+func (s *MemoryStore) UpdateFields(ctx context.Context, id string, fields map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(id)
+	if i < 0 {
+		return ErrItemNotFound
+	}
+	n, err := patch(s.notes[i], fields)
+	if err != nil {
+		return err
+	}
+	s.notes[i] = n
+	return nil
+}
+
+// patch returns a copy of n with fields, keyed by JSON name, set.
+func patch(n *Note, fields map[string]interface{}) (*Note, error) {
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	patched := cloneNote(n)
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(patched); err != nil {
+		return nil, fmt.Errorf("grocery: bad fields for note %s: %w", n.ID, err)
+	}
+	patched.ID = n.ID
+	return patched, nil
+}
+
+// Delete removes the note with n's ID, returning ErrItemNotFound if there is
+// no such note.
+func (s *MemoryStore) Delete(ctx context.Context, n *Note) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(n.ID)
+	if i < 0 {
+		return ErrItemNotFound
+	}
+	s.notes = append(s.notes[:i], s.notes[i+1:]...)
+	return nil
+}
+
+// DeleteMany deletes notes, reporting those that don't exist in a
+// *BatchError.
+func (s *MemoryStore) DeleteMany(ctx context.Context, notes []*Note) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var failures []BatchFailure
+	for i, n := range notes {
+		j := s.index(n.ID)
+		if j < 0 {
+			failures = append(failures, BatchFailure{Index: i, Message: ErrItemNotFound.Error()})
+			continue
+		}
+		s.notes = append(s.notes[:j], s.notes[j+1:]...)
+	}
+	if len(failures) > 0 {
+		return &BatchError{Failures: failures}
+	}
+	return nil
+}
+
+func (s *MemoryStore) ReplaceAll(ctx context.Context, notes []*Note) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.notes
+	s.notes = nil
+	for _, n := range notes {
+		if err := s.create(n); err != nil {
+			s.notes = old
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) DeleteAll(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.notes = nil
+	return nil
+}
+
+func (s *MemoryStore) index(id string) int {
+	for i, n := range s.notes {
+		if n.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func cloneNote(n *Note) *Note {
+	c := *n
+	c.Meta = maps.Clone(n.Meta)
+	return &c
+}
+
+func cloneNotes(notes []*Note) []*Note {
+	clones := make([]*Note, len(notes))
+	for i, n := range notes {
+		clones[i] = cloneNote(n)
+	}
+	return clones
+}
@@ -0,0 +1,276 @@
+package grocery
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMemoryStoreGroceryList(t *testing.T) {
+	ctx := context.Background()
+	list := New()
+	list.Store = &MemoryStore{}
+
+	for _, item := range []string{"apples", "milk", "bread", "apples"} {
+		if err := list.AddItem(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := list.MarkPurchased(ctx, "milk"); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.RemoveItem(ctx, "bread"); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.UpdateItem(ctx, "apples", "green apples"); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := list.Items(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(items, ",") != "green apples,milk" {
+		t.Fatal("expected green apples and milk but was", items)
+	}
+	pending, err := list.Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(pending, ",") != "green apples" {
+		t.Fatal("expected only green apples pending but was", pending)
+	}
+}
+
+func TestMemoryStoreSnapshotRestore(t *testing.T) {
+	ctx := context.Background()
+	list := New()
+	list.Store = NewMemoryStore(&Note{Text: "apples", Quantity: 2}, &Note{Text: "milk", Purchased: true})
+
+	snapshot, err := list.Snapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := list.ClearAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.AddItem(ctx, "bread"); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.Restore(ctx, snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := list.ItemsWithQuantity(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0] != (Item{Text: "apples", Quantity: 2}) || items[1] != (Item{Text: "milk"}) {
+		t.Fatalf("expected the snapshot's apples and milk but was %+v", items)
+	}
+	pending, err := list.Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(pending, ",") != "apples" {
+		t.Fatal("expected only apples pending but was", pending)
+	}
+}
+
+func TestMemoryStoreReorder(t *testing.T) {
+	ctx := context.Background()
+	list := New()
+	list.Store = NewMemoryStore(&Note{Text: "apples"}, &Note{Text: "milk"}, &Note{Text: "bread"}, &Note{Text: "eggs"})
+
+	if err := list.Reorder(ctx, []string{"bread", "apples"}); err != nil {
+		t.Fatal(err)
+	}
+	items, err := list.Items(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(items, ",") != "bread,apples,milk,eggs" {
+		t.Fatal("expected bread, apples, milk, eggs but was", items)
+	}
+
+	if err := list.Reorder(ctx, []string{"eggs"}); err != nil {
+		t.Fatal(err)
+	}
+	items, err = list.Items(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(items, ",") != "eggs,bread,apples,milk" {
+		t.Fatal("expected eggs, bread, apples, milk but was", items)
+	}
+
+	if err := list.Reorder(ctx, []string{"cheese"}); !errors.Is(err, ErrItemNotFound) {
+		t.Fatal("expected ErrItemNotFound but was", err)
+	}
+}
+
+func TestMemoryStoreAllPageNegative(t *testing.T) {
+	ctx := context.Background()
+	store := &MemoryStore{}
+	for _, text := range []string{"apples", "milk", "bread"} {
+		if err := store.Create(ctx, &Note{Text: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, total, err := store.AllPage(ctx, 2, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || strings.Join(texts(page), ",") != "apples,milk" {
+		t.Fatal("expected apples and milk of 3 but was", texts(page), total)
+	}
+	page, total, err = store.AllPage(ctx, -1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || len(page) != 0 {
+		t.Fatal("expected an empty page of 3 but was", texts(page), total)
+	}
+}
+
+func TestMemoryStoreCopiesNotes(t *testing.T) {
+	ctx := context.Background()
+	store := &MemoryStore{Sequential: true}
+
+	n := &Note{Text: "apples"}
+	store.Create(ctx, n)
+	n.Text = "bananas"
+
+	notes, _ := store.All(ctx)
+	notes[0].Quantity = 99
+
+	notes, _ = store.All(ctx)
+	if !notes[0].Equal(&Note{ID: "1", Text: "apples"}) {
+		t.Fatalf("expected the stored note to be unchanged but was %+v", notes[0])
+	}
+}
+
+func TestMemoryStoreDefaultIDs(t *testing.T) {
+	seed := &Note{Text: "apples"}
+	store := NewMemoryStore(seed)
+	if seed.ID != "" {
+		t.Error("expected the seed note to be left alone but its ID was", seed.ID)
+	}
+
+	n := &Note{Text: "milk"}
+	if err := store.Create(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	notes, _ := store.All(context.Background())
+	for _, n := range notes {
+		if len(n.ID) != 36 || strings.Count(n.ID, "-") != 4 {
+			t.Errorf("expected a UUID for %s but was %q", n.Text, n.ID)
+		}
+	}
+	if notes[0].ID == notes[1].ID {
+		t.Error("expected distinct IDs but both were", notes[0].ID)
+	}
+}
+
+func TestMemoryStoreUpdateFields(t *testing.T) {
+	ctx := context.Background()
+	store := &MemoryStore{Sequential: true}
+	store.Create(ctx, &Note{Text: "apples", Quantity: 1, Category: "produce"})
+
+	if err := store.UpdateFields(ctx, "1", map[string]interface{}{"quantity": 4, "purchased": true}); err != nil {
+		t.Fatal(err)
+	}
+	notes, _ := store.All(ctx)
+	if !notes[0].Equal(&Note{ID: "1", Text: "apples", Quantity: 4, Purchased: true, Category: "produce"}) {
+		t.Fatalf("expected only quantity and purchased to change but was %+v", notes[0])
+	}
+	if err := store.UpdateFields(ctx, "1", map[string]interface{}{"colour": "red"}); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+	if err := store.UpdateFields(ctx, "2", map[string]interface{}{"quantity": 4}); err != ErrItemNotFound {
+		t.Error("expected ErrItemNotFound but was", err)
+	}
+}
+
+func TestMemoryStoreDeleteMany(t *testing.T) {
+	ctx := context.Background()
+	store := &MemoryStore{Sequential: true}
+	store.CreateMany(ctx, []*Note{{Text: "apples"}, {Text: "milk"}, {Text: "bread"}})
+
+	err := store.DeleteMany(ctx, []*Note{{ID: "1"}, {ID: "9"}, {ID: "3"}})
+	var be *BatchError
+	if !errors.As(err, &be) || len(be.Failures) != 1 || be.Failures[0].Index != 1 {
+		t.Fatal("expected the second note to fail but was", err)
+	}
+	notes, _ := store.All(ctx)
+	if len(notes) != 1 || notes[0].Text != "milk" {
+		t.Fatal("expected only milk to survive but was", formatNotes(notes))
+	}
+}
+
+func TestMemoryStoreGetMany(t *testing.T) {
+	store := NewMemoryStore(&Note{Text: "apples", Quantity: 3}, &Note{Text: "milk", Quantity: 1})
+	notes, err := store.GetMany(context.Background(), []string{"milk", "bread", "apples"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 2 || notes["apples"].Quantity != 3 || notes["milk"].Quantity != 1 {
+		t.Fatalf("expected apples and milk but was %v", notes)
+	}
+	if _, ok := notes["bread"]; ok {
+		t.Error("expected bread to be left out")
+	}
+}
+
+func TestMemoryStoreMissingNote(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(&Note{Text: "apples"})
+
+	if err := store.Update(ctx, &Note{ID: "2", Text: "milk"}); err != ErrItemNotFound {
+		t.Error("expected ErrItemNotFound updating a missing note but was", err)
+	}
+	if err := store.Delete(ctx, &Note{ID: "2"}); err != ErrItemNotFound {
+		t.Error("expected ErrItemNotFound deleting a missing note but was", err)
+	}
+}
+
+// sequence returns an IDGen giving ids in turn.
+func sequence(ids ...string) func() string {
+	i := 0
+	return func() string {
+		id := ids[i%len(ids)]
+		i++
+		return id
+	}
+}
+
+func TestMemoryStoreIDGen(t *testing.T) {
+	ctx := context.Background()
+	store := &MemoryStore{IDGen: sequence("a", "a", "b")}
+
+	apples := &Note{Text: "apples"}
+	if err := store.Create(ctx, apples); err != nil {
+		t.Fatal(err)
+	}
+	milk := &Note{Text: "milk"}
+	if err := store.Create(ctx, milk); err != nil {
+		t.Fatal(err)
+	}
+	if apples.ID != "a" || milk.ID != "b" {
+		t.Fatalf("expected IDs a and b, skipping the repeated a, but were %q and %q", apples.ID, milk.ID)
+	}
+	n, err := store.Get(ctx, "milk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.ID != "b" {
+		t.Error("expected milk stored under b but was", n.ID)
+	}
+
+	store.IDGen = sequence("a")
+	if err := store.Create(ctx, &Note{Text: "bread"}); err == nil {
+		t.Error("expected an error when IDGen only gives IDs in use")
+	}
+}
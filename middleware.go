@@ -0,0 +1,50 @@
+package grocery
+
+import "time"
+
+// Middleware wraps an API in another, such as a CachingStore.
+type Middleware func(API) API
+
+// Chain wraps base in each of mw in turn, so the last is outermost and sees
+// every call first: Chain(client, RetryMiddleware(p), CacheMiddleware(ttl))
+// serves All from the cache and retries only the calls that reach client.
+//
+// The stores these Middleware build forward Count and Close but none of the
+// other optional interfaces, so a Pager, Streamer, Searcher, Sorter, Checker,
+// Categorizer or Clearer at base is hidden behind the chain and GroceryList
+// falls back to All for those calls.
+func Chain(base API, mw ...Middleware) API {
+	store := base
+	for _, m := range mw {
+		store = m(store)
+	}
+	return store
+}
+
+// RetryMiddleware is the Middleware of NewRetryStore.
+func RetryMiddleware(p RetryPolicy) Middleware {
+	return func(store API) API {
+		return NewRetryStore(store, p)
+	}
+}
+
+// CacheMiddleware is the Middleware of NewCachingStore.
+func CacheMiddleware(ttl time.Duration) Middleware {
+	return func(store API) API {
+		return NewCachingStore(store, ttl)
+	}
+}
+
+// MetricsMiddleware is the Middleware of NewInstrumentedStore.
+func MetricsMiddleware(rec MetricsRecorder) Middleware {
+	return func(store API) API {
+		return NewInstrumentedStore(store, rec)
+	}
+}
+
+// CircuitBreakerMiddleware is the Middleware of NewCircuitBreakerStore.
+func CircuitBreakerMiddleware(threshold int, cooldown time.Duration) Middleware {
+	return func(store API) API {
+		return NewCircuitBreakerStore(store, threshold, cooldown)
+	}
+}
@@ -0,0 +1,68 @@
+package grocery
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// tracingStore logs its name on each All call before passing it on.
+type tracingStore struct {
+	API
+	name string
+	log  *[]string
+}
+
+func (s *tracingStore) All(ctx context.Context) ([]*Note, error) {
+	*s.log = append(*s.log, s.name)
+	return s.API.All(ctx)
+}
+
+func tracing(name string, log *[]string) Middleware {
+	return func(store API) API {
+		return &tracingStore{store, name, log}
+	}
+}
+
+func TestChain(t *testing.T) {
+	var log []string
+	store := Chain(NewMemoryStore(), tracing("inner", &log), tracing("middle", &log), tracing("outer", &log))
+
+	if _, err := store.All(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"outer", "middle", "inner"}; !reflect.DeepEqual(log, want) {
+		t.Errorf("expected the call to pass through %q but was %q", want, log)
+	}
+	if Chain(store) != store {
+		t.Error("expected a chain without middleware to be its base")
+	}
+}
+
+func TestChainDecorators(t *testing.T) {
+	ctx := context.Background()
+	base := &flakyStore{MemoryStore: NewMemoryStore(&Note{Text: "apples"}), fails: 1}
+	rec := &fakeRecorder{}
+	store := Chain(base,
+		RetryMiddleware(RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond, Retryable: retryFlaky}),
+		CacheMiddleware(time.Minute),
+		MetricsMiddleware(rec),
+	)
+
+	for i := 0; i < 2; i++ {
+		notes, err := store.All(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(notes) != 1 {
+			t.Fatalf("expected apples but was %s", formatNotes(notes))
+		}
+	}
+	if len(rec.observations) != 2 {
+		t.Error("expected both calls observed but was", len(rec.observations))
+	}
+	if base.attempts != 2 {
+		t.Error("expected one cached fetch, retried once, but the store saw", base.attempts, "attempts")
+	}
+}
@@ -0,0 +1,38 @@
+package grocery
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NormalizeSpace trims item and collapses each run of whitespace within it
+// to a single space. It is the default GroceryList.Normalizer.
+func NormalizeSpace(item string) string {
+	return strings.Join(strings.Fields(item), " ")
+}
+
+// NormalizeTitle is NormalizeSpace that also capitalizes the first letter of
+// every word, so "green  apples" becomes "Green Apples". The rest of each
+// word is left as it is.
+func NormalizeTitle(item string) string {
+	words := strings.Fields(item)
+	for i, w := range words {
+		r, size := utf8.DecodeRuneInString(w)
+		words[i] = string(unicode.ToUpper(r)) + w[size:]
+	}
+	return strings.Join(words, " ")
+}
+
+// normalize returns item as g.Normalizer makes it.
+func (g *GroceryList) normalize(item string) string {
+	if g.Normalizer == nil {
+		return NormalizeSpace(item)
+	}
+	return g.Normalizer(item)
+}
+
+// key returns what item is compared by under g.DedupMode, once normalized.
+func (g *GroceryList) key(item string) string {
+	return g.DedupMode.key(g.normalize(item))
+}
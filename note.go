@@ -1,25 +1,190 @@
 package grocery
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+	"time"
+)
+
+// API is the store behind a GroceryList. Implementations must be safe for
+// concurrent use.
 type API interface {
-	Create(*Note) error
-	All() ([]*Note, error)
+	Create(ctx context.Context, n *Note) error
+	CreateMany(ctx context.Context, notes []*Note) error
+	All(ctx context.Context) ([]*Note, error)
+	// Get returns the note whose text is text, or ErrItemNotFound.
+	Get(ctx context.Context, text string) (*Note, error)
+	// GetMany returns the notes whose texts are among texts, keyed by text.
+	// Texts without a note are left out.
+	GetMany(ctx context.Context, texts []string) (map[string]*Note, error)
+	Update(ctx context.Context, n *Note) error
+	// UpdateFields sets only the given fields, keyed by their JSON names, of
+	// the note with ID id.
+	UpdateFields(ctx context.Context, id string, fields map[string]interface{}) error
+	Delete(ctx context.Context, n *Note) error
+	// DeleteMany deletes notes in one go. Notes that couldn't be deleted
+	// are reported by index in a *BatchError.
+	DeleteMany(ctx context.Context, notes []*Note) error
+	// ReplaceAll replaces every note with notes in one go, setting their
+	// IDs. No notes clears the store.
+	ReplaceAll(ctx context.Context, notes []*Note) error
+	// Ping reports whether the store is reachable.
+	Ping(ctx context.Context) error
 }
 
-type Note struct {
-	Text string
+// Counter is implemented by stores that can count notes without fetching
+// them.
+type Counter interface {
+	Count(ctx context.Context) (int, error)
+}
+
+// countNotes returns how many notes store holds: from Count if it is a
+// Counter, or else from the total of a one-note AllPage if it is a Pager.
+// Only a store that is neither has every note fetched to count them.
+func countNotes(ctx context.Context, store API) (int, error) {
+	if counter, ok := store.(Counter); ok {
+		return counter.Count(ctx)
+	}
+	if pager, ok := store.(Pager); ok {
+		_, total, err := pager.AllPage(ctx, 1, 0)
+		return total, err
+	}
+	notes, err := store.All(ctx)
+	return len(notes), err
+}
+
+// Pager is implemented by stores that can fetch notes a page at a time.
+// AllPage returns up to limit notes starting at offset, plus the total number
+// of notes in the store.
+type Pager interface {
+	AllPage(ctx context.Context, limit, offset int) ([]*Note, int, error)
+}
+
+// Searcher is implemented by stores that can search notes server-side, so
+// the whole list doesn't need fetching to find a few items.
+type Searcher interface {
+	Search(ctx context.Context, query string) ([]*Note, error)
+}
+
+// Checker is implemented by stores that can check server-side whether a note
+// with the given text exists, so the whole list doesn't need fetching.
+type Checker interface {
+	Has(ctx context.Context, text string) (bool, error)
+}
+
+// Categorizer is implemented by stores that can list the categories in use
+// server-side, so the whole list doesn't need fetching. An empty category
+// stands for the notes without one.
+type Categorizer interface {
+	Categories(ctx context.Context) ([]string, error)
+}
+
+// Clearer is implemented by stores that can delete every note at once.
+type Clearer interface {
+	DeleteAll(ctx context.Context) error
 }
 
-type HTTPClient struct {
+// Sorter is implemented by stores that can sort notes server-side. Field is
+// the JSON name of one of SortFields, and desc sorts it highest first.
+type Sorter interface {
+	AllSorted(ctx context.Context, field string, desc bool) ([]*Note, error)
 }
 
-func (c *HTTPClient) Create(n *Note) error {
-	// some implementation
+// SortFields are the fields a Sorter may be asked to sort by.
+var SortFields = []string{"text", "quantity", "created_at"}
 
+// checkSortField returns an ErrUnknownSortField error unless field is one
+// of SortFields.
+func checkSortField(field string) error {
+	for _, f := range SortFields {
+		if f == field {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownSortField, field)
+}
+
+// Streamer is implemented by stores that can deliver notes as they are
+// read instead of all at once. The error channel receives at most one error
+// and both channels are closed when the stream ends.
+type Streamer interface {
+	AllStream(ctx context.Context) (<-chan *Note, <-chan error)
+}
+
+// Stores holding connections or other resources may implement io.Closer.
+// GroceryList.Close closes such a store, and the stores that wrap another
+// close the one they wrap.
+func closeStore(store API) error {
+	if closer, ok := store.(io.Closer); ok {
+		return closer.Close()
+	}
 	return nil
 }
 
-func (c *HTTPClient) All() ([]*Note, error) {
-	// some implementation
+// Note is a single entry in the store. ID is assigned by the store when the
+// note is created and is what Update and Delete use to find the note.
+// CreatedAt and UpdatedAt are likewise set by the store, when it keeps them.
+// Unit is what Quantity counts, such as "lb" or "oz"; empty means a plain
+// count. Order positions the note among the others, lowest first; see
+// Reorder. Archived notes are kept but left out of the active list; see
+// ArchiveItem. Version, when the store keeps it, counts the note's updates,
+// so an update made from an outdated copy can be refused with ErrConflict.
+// Meta holds whatever else users attach to the item, such as a preferred
+// brand; see SetMeta.
+type Note struct {
+	ID        string            `json:"id,omitempty"`
+	Text      string            `json:"text"`
+	Quantity  int               `json:"quantity"`
+	Unit      string            `json:"unit,omitempty"`
+	Purchased bool              `json:"purchased"`
+	Category  string            `json:"category"`
+	Archived  bool              `json:"archived,omitempty"`
+	Version   int               `json:"version,omitempty"`
+	Order     int               `json:"order,omitempty"`
+	CreatedAt time.Time         `json:"created_at,omitzero"`
+	UpdatedAt time.Time         `json:"updated_at,omitzero"`
+	Meta      map[string]string `json:"meta,omitempty"`
+}
+
+// Validate reports every problem with n that keeps it from being created,
+// joined: ErrEmptyItem for blank text, ErrNegativeQuantity, and
+// ErrUnitWithoutQuantity for a unit given with no quantity.
+func (n *Note) Validate() error {
+	var errs []error
+	if strings.TrimSpace(n.Text) == "" {
+		errs = append(errs, ErrEmptyItem)
+	}
+	if n.Quantity < 0 {
+		errs = append(errs, ErrNegativeQuantity)
+	}
+	if n.Unit != "" && n.Quantity == 0 {
+		errs = append(errs, ErrUnitWithoutQuantity)
+	}
+	return errors.Join(errs...)
+}
 
-	return []*Note{}, nil
+// Equal reports whether n and other hold the same note. Texts are compared
+// with surrounding space trimmed, and timestamps as instants, whatever their
+// location or monotonic reading. A nil Meta equals an empty one. Two nil
+// notes are equal.
+func (n *Note) Equal(other *Note) bool {
+	if n == nil || other == nil {
+		return n == other
+	}
+	return n.ID == other.ID &&
+		strings.TrimSpace(n.Text) == strings.TrimSpace(other.Text) &&
+		n.Quantity == other.Quantity &&
+		n.Unit == other.Unit &&
+		n.Purchased == other.Purchased &&
+		n.Category == other.Category &&
+		n.Archived == other.Archived &&
+		n.Order == other.Order &&
+		n.Version == other.Version &&
+		n.CreatedAt.Equal(other.CreatedAt) &&
+		n.UpdatedAt.Equal(other.UpdatedAt) &&
+		maps.Equal(n.Meta, other.Meta)
 }
@@ -1,75 +1,3545 @@
 package grocery
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
 
 type Call interface{}
 
+const defaultFakeTimeout = 2 * time.Second
+
+// FakeClient is an API for tests, driven from the test through Assert,
+// Stub and Expect calls. Assertions compare notes with Note.Equal.
 type FakeClient struct {
-	t     *testing.T
-	Calls chan Call
+	*chanFake
+
+	mu           sync.Mutex
+	stubs        map[string][]Call
+	recorded     []Call
+	counts       map[string]int
+	unordered    bool
+	expectations []*expectation
+	defaults     map[string]Call
+	scripted     bool
+	script       []*expectation
+	delegate     API
+	responses    []Call
+	answered     []Call
+}
+
+func NewFakeClient(t testing.TB) *FakeClient {
+	return NewFakeClientWithTimeout(t, defaultFakeTimeout)
+}
+
+// NewSpyClient returns a FakeClient that answers every call by forwarding it
+// to delegate, unless a stub answers it first. Calls and their responses
+// are recorded as usual.
+func NewSpyClient(t testing.TB, delegate API) *FakeClient {
+	c := NewFakeClient(t)
+	c.delegate = delegate
+	return c
+}
+
+// NewFakeClientWithTimeout returns a FakeClient that fails the test when
+// either side of a call waits longer than d for the other.
+func NewFakeClientWithTimeout(t testing.TB, d time.Duration) *FakeClient {
+	return &FakeClient{
+		chanFake: newChanFake(t, d),
+		stubs:    map[string][]Call{},
+		counts:   map[string]int{},
+		defaults: map[string]Call{},
+	}
+}
+
+// stub queues resp as the answer to the next call to method, so that call is
+// answered without an assertion goroutine.
+func (c *FakeClient) stub(method string, resp Call) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stubs[method] = append(c.stubs[method], resp)
+}
+
+func (c *FakeClient) StubAll(notes []*Note, err error) {
+	c.stub("All", &allResp{notes, err})
+}
+
+// QueueAll queues responses for the next All calls, answered in order
+// without an AssertAll each. Once they run out All is answered as usual.
+func (c *FakeClient) QueueAll(responses ...allResp) {
+	for i := range responses {
+		c.stub("All", &responses[i])
+	}
+}
+
+func (c *FakeClient) StubCount(n int, err error) {
+	c.stub("Count", &countResp{n, err})
+}
+
+func (c *FakeClient) StubCreate(err error) {
+	c.stub("Create", &createResp{err: err})
+}
+
+func (c *FakeClient) StubCreateMany(err error) {
+	c.stub("CreateMany", &createManyResp{err})
+}
+
+func (c *FakeClient) StubUpdate(err error) {
+	c.stub("Update", &updateResp{err})
+}
+
+func (c *FakeClient) StubDelete(err error) {
+	c.stub("Delete", &deleteResp{err})
+}
+
+func (c *FakeClient) StubPing(err error) {
+	c.stub("Ping", &pingResp{err})
+}
+
+// DefaultAll answers every All call with notes and err, except when an
+// AssertAll is already waiting for the call.
+func (c *FakeClient) DefaultAll(notes []*Note, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.defaults["All"] = &allResp{notes, err}
+}
+
+// StubItems answers every All call with Notes(texts...), as DefaultAll does.
+func (c *FakeClient) StubItems(texts ...string) {
+	c.DefaultAll(Notes(texts...), nil)
+}
+
+// DefaultCreate answers every Create call with err, except when an
+// AssertCreate is already waiting for the call.
+func (c *FakeClient) DefaultCreate(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.defaults["Create"] = &createResp{err: err}
+}
+
+// fakeCall is a call made to the FakeClient, as recorded and as sent on
+// Calls to the assertion side.
+type fakeCall interface {
+	method() string
+	String() string
+	// forward makes the call to store and returns its response.
+	forward(store API) Call
+}
+
+var errUnexpectedCall = errors.New("fake: unexpected call")
+
+// expectation is a call registered up front in unordered mode. The first
+// call to method that match accepts is answered with resp.
+type expectation struct {
+	method string
+	desc   string
+	match  func(Call) bool
+	resp   Call
+}
+
+// expectUnordered switches the client into unordered mode, where calls are
+// answered by whichever registered expectation they match rather than by an
+// assertion goroutine, and any call matching no expectation fails the test.
+func (c *FakeClient) expectUnordered(e *expectation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.unordered = true
+	c.expectations = append(c.expectations, e)
+}
+
+func anyCall(Call) bool { return true }
+
+func allExpectation() *expectation {
+	return &expectation{method: "All", desc: "All()", match: anyCall, resp: &allResp{}}
+}
+
+func countExpectation() *expectation {
+	return &expectation{method: "Count", desc: "Count()", match: anyCall, resp: &countResp{}}
+}
+
+func createExpectation(n *Note) *expectation {
+	return &expectation{
+		method: "Create",
+		desc:   fmt.Sprintf("Create(%+v)", *n),
+		match:  func(call Call) bool { return call.(*createCall).note.Equal(n) },
+		resp:   &createResp{},
+	}
+}
+
+func createManyExpectation(notes []*Note) *expectation {
+	return &expectation{
+		method: "CreateMany",
+		desc:   fmt.Sprintf("CreateMany(%s)", formatNotes(notes)),
+		match:  func(call Call) bool { return sameNotes(call.(*createManyCall).notes, notes) },
+		resp:   &createManyResp{},
+	}
+}
+
+func updateExpectation(n *Note) *expectation {
+	return &expectation{
+		method: "Update",
+		desc:   fmt.Sprintf("Update(%+v)", *n),
+		match:  func(call Call) bool { return call.(*updateCall).note.Equal(n) },
+		resp:   &updateResp{},
+	}
+}
+
+func deleteExpectation(n *Note) *expectation {
+	return &expectation{
+		method: "Delete",
+		desc:   fmt.Sprintf("Delete(%+v)", *n),
+		match:  func(call Call) bool { return call.(*deleteCall).note.Equal(n) },
+		resp:   &deleteResp{},
+	}
+}
+
+func replaceAllExpectation(notes []*Note) *expectation {
+	return &expectation{
+		method: "ReplaceAll",
+		desc:   fmt.Sprintf("ReplaceAll(%s)", formatNotes(notes)),
+		match:  func(call Call) bool { return sameNotes(call.(*replaceAllCall).notes, notes) },
+		resp:   &replaceAllResp{},
+	}
+}
+
+func (c *FakeClient) ExpectAll(notes []*Note, err error) {
+	e := allExpectation()
+	e.resp = &allResp{notes, err}
+	c.expectUnordered(e)
+}
+
+func (c *FakeClient) ExpectCount(n int, err error) {
+	e := countExpectation()
+	e.resp = &countResp{n, err}
+	c.expectUnordered(e)
+}
+
+func (c *FakeClient) ExpectCreate(n *Note, err error) {
+	e := createExpectation(n)
+	e.resp = &createResp{err: err}
+	c.expectUnordered(e)
+}
+
+func (c *FakeClient) ExpectCreateMany(notes []*Note, err error) {
+	e := createManyExpectation(notes)
+	e.resp = &createManyResp{err}
+	c.expectUnordered(e)
+}
+
+func (c *FakeClient) ExpectUpdate(n *Note, err error) {
+	e := updateExpectation(n)
+	e.resp = &updateResp{err}
+	c.expectUnordered(e)
+}
+
+func (c *FakeClient) ExpectDelete(n *Note, err error) {
+	e := deleteExpectation(n)
+	e.resp = &deleteResp{err}
+	c.expectUnordered(e)
+}
+
+// Script is an ordered sequence of expected calls, built fluently:
+//
+//	client.Expect().Create(n).Returns(nil).Then().All().Returns(notes, nil)
+//
+// Once a client has a script, each call must match the next step and is
+// answered with that step's response; steps that return nothing answer with
+// zero values. AssertExpectationsMet reports any steps left over.
+type Script struct{ c *FakeClient }
+
+// ScriptStep is a step of a Script that has its response.
+type ScriptStep struct{ script *Script }
+
+func (s *ScriptStep) Then() *Script { return s.script }
+
+func (c *FakeClient) Expect() *Script {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.scripted = true
+	return &Script{c}
+}
+
+func (s *Script) add(e *expectation) *expectation {
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+	s.c.script = append(s.c.script, e)
+	return e
+}
+
+// respond sets the response of step e.
+func (s *Script) respond(e *expectation, resp Call) *ScriptStep {
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+	e.resp = resp
+	return &ScriptStep{s}
+}
+
+type AllStep struct {
+	script *Script
+	e      *expectation
+}
+
+func (s *Script) All() *AllStep { return &AllStep{s, s.add(allExpectation())} }
+
+func (s *AllStep) Returns(notes []*Note, err error) *ScriptStep {
+	return s.script.respond(s.e, &allResp{notes, err})
+}
+
+type CountStep struct {
+	script *Script
+	e      *expectation
+}
+
+func (s *Script) Count() *CountStep { return &CountStep{s, s.add(countExpectation())} }
+
+func (s *CountStep) Returns(n int, err error) *ScriptStep {
+	return s.script.respond(s.e, &countResp{n, err})
+}
+
+// ErrorStep is a step for a call that only returns an error.
+type ErrorStep struct {
+	script *Script
+	e      *expectation
+	resp   func(error) Call
+}
+
+func (s *ErrorStep) Returns(err error) *ScriptStep {
+	return s.script.respond(s.e, s.resp(err))
+}
+
+func (s *Script) Create(n *Note) *ErrorStep {
+	return &ErrorStep{s, s.add(createExpectation(n)), func(err error) Call { return &createResp{err: err} }}
+}
+
+func (s *Script) CreateMany(notes []*Note) *ErrorStep {
+	return &ErrorStep{s, s.add(createManyExpectation(notes)), func(err error) Call { return &createManyResp{err} }}
+}
+
+func (s *Script) Update(n *Note) *ErrorStep {
+	return &ErrorStep{s, s.add(updateExpectation(n)), func(err error) Call { return &updateResp{err} }}
+}
+
+func (s *Script) Delete(n *Note) *ErrorStep {
+	return &ErrorStep{s, s.add(deleteExpectation(n)), func(err error) Call { return &deleteResp{err} }}
+}
+
+// AssertDone fails the test unless the client is closed with no more calls
+// made and every registered expectation and script step was matched, so it
+// checks both that everything expected happened and that nothing else did.
+func (c *FakeClient) AssertDone() {
+	c.chanFake.AssertDone()
+	c.AssertExpectationsMet()
+}
+
+// AssertExpectationsMet fails the test for every registered expectation and
+// script step no call has matched.
+func (c *FakeClient) AssertExpectationsMet() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.expectations {
+		c.t.Errorf("expected %s but it was never called", e.desc)
+	}
+	for _, e := range c.script {
+		c.t.Errorf("expected scripted %s but the script stopped short", e.desc)
+	}
+}
+
+// next answers call from the next script step. c.mu must be held.
+func (c *FakeClient) next(call fakeCall) (Call, error) {
+	if len(c.script) == 0 {
+		c.t.Errorf("unexpected call %s after the script ran out", call)
+		return nil, errUnexpectedCall
+	}
+	e := c.script[0]
+	c.script = c.script[1:]
+	if e.method != call.method() || !e.match(call) {
+		c.t.Errorf("expected scripted %s but got %s", e.desc, call)
+		return nil, errUnexpectedCall
+	}
+	return e.resp, nil
+}
+
+// match answers call from the first expectation it satisfies. c.mu must be
+// held.
+func (c *FakeClient) match(method string, call Call) (Call, bool) {
+	for i, e := range c.expectations {
+		if e.method == method && e.match(call) {
+			c.expectations = append(c.expectations[:i:i], c.expectations[i+1:]...)
+			return e.resp, true
+		}
+	}
+	return nil, false
+}
+
+// PeekCall returns the oldest of the calls answered without an assertion,
+// by stubs, defaults, expectations, a script or a delegate, that NextCall
+// hasn't taken, leaving it for NextCall. A test can so branch on what it
+// is, such as with a type switch on *allCall and *createCall, before
+// asserting on it. It reports false, without waiting, if there is none.
+// Calls left to an assertion on the blocking Calls channel never reach
+// PeekCall, since they wait for the assertion to be answered.
+func (c *FakeClient) PeekCall() (Call, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.answered) == 0 {
+		return nil, false
+	}
+	return c.answered[0], true
+}
+
+// NextCall takes the call PeekCall returns.
+func (c *FakeClient) NextCall() (Call, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.answered) == 0 {
+		return nil, false
+	}
+	call := c.answered[0]
+	c.answered = c.answered[1:]
+	return call, true
+}
+
+// RecordedCalls returns every call made so far, in order.
+func (c *FakeClient) RecordedCalls() []Call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Call{}, c.recorded...)
+}
+
+// AssertCallSequence fails the test unless the calls recorded so far are
+// exactly expected, in order, built with the same helpers as unordered
+// expectations, such as createExpectation. On a mismatch both sequences are
+// listed side by side.
+func (c *FakeClient) AssertCallSequence(expected ...*expectation) {
+	c.t.Helper()
+	calls := c.RecordedCalls()
+
+	ok := len(calls) == len(expected)
+	for i := 0; ok && i < len(calls); i++ {
+		call := calls[i].(fakeCall)
+		ok = call.method() == expected[i].method && expected[i].match(call)
+	}
+	if ok {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("expected call sequence:")
+	for i := 0; i < max(len(calls), len(expected)); i++ {
+		want, got := "(none)", "(none)"
+		if i < len(expected) {
+			want = expected[i].desc
+		}
+		if i < len(calls) {
+			got = calls[i].(fakeCall).String()
+		}
+		mark := " "
+		if want != got {
+			mark = "!"
+		}
+		fmt.Fprintf(&b, "\n%s %d: expected %s\n     but was %s", mark, i+1, want, got)
+	}
+	c.t.Error(b.String())
+}
+
+// RecordedResponses returns the response to each of RecordedCalls, nil for
+// calls that failed in the fake itself or are still waiting for an answer.
+func (c *FakeClient) RecordedResponses() []Call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Call{}, c.responses...)
+}
+
+// AssertCreateReturned fails the test unless the last Create call was
+// answered with an error matching wantErr, or with none if wantErr is nil.
+// Together with checking the error the code under test returned, it shows
+// the error got from the fake to the caller. Like the count assertions it
+// reads what the client recorded, so it works after Close.
+func (c *FakeClient) AssertCreateReturned(wantErr error) {
+	c.t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := len(c.recorded) - 1; i >= 0; i-- {
+		if c.recorded[i].(fakeCall).method() != "Create" {
+			continue
+		}
+		resp, ok := c.responses[i].(*createResp)
+		if !ok {
+			c.t.Errorf("expected Create to return %v but it was not answered", wantErr)
+		} else if !errors.Is(resp.err, wantErr) {
+			c.t.Errorf("expected Create to return %v but it returned %v", wantErr, resp.err)
+		}
+		return
+	}
+	c.t.Errorf("expected Create to return %v but it was never called", wantErr)
+}
+
+// AssertCreateCount fails the test unless exactly n Create calls have been
+// made. Like the other count assertions it reads what the client recorded,
+// so it works after Close.
+func (c *FakeClient) AssertCreateCount(n int) {
+	c.assertCount("Create", n)
+}
+
+func (c *FakeClient) AssertAllCount(n int) {
+	c.assertCount("All", n)
+}
+
+func (c *FakeClient) assertCount(method string, n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if got := c.counts[method]; got != n {
+		c.t.Errorf("expected %d %s calls but there were %d", n, method, got)
+	}
+}
+
+// AssertCallCount fails the test unless exactly total calls of any kind have
+// been made.
+func (c *FakeClient) AssertCallCount(total int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if got := len(c.recorded); got != total {
+		c.t.Errorf("expected %d calls but there were %d", total, got)
+	}
+}
+
+// AssertNoCalls fails the test, listing the calls, if any call has been
+// made, for tests where the store must not be touched at all.
+func (c *FakeClient) AssertNoCalls() {
+	c.t.Helper()
+	calls := c.RecordedCalls()
+	if len(calls) == 0 {
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "expected no calls but there were %d:", len(calls))
+	for _, call := range calls {
+		fmt.Fprintf(&b, "\n\t%s", call.(fakeCall))
+	}
+	c.t.Error(b.String())
+}
+
+// call records call, answers it and records the response.
+func (c *FakeClient) call(ctx context.Context, call fakeCall) (Call, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.recorded = append(c.recorded, call)
+	c.responses = append(c.responses, nil)
+	i := len(c.recorded) - 1
+	c.mu.Unlock()
+
+	resp, answered, err := c.answer(call)
+	if answered {
+		c.mu.Lock()
+		c.answered = append(c.answered, call)
+		c.mu.Unlock()
+	} else {
+		resp, err = c.roundTrip(ctx, call.method(), call)
+	}
+	if err == nil {
+		c.mu.Lock()
+		c.responses[i] = resp
+		c.mu.Unlock()
+	}
+	return resp, err
+}
+
+// answer returns the response to call without an assertion, if it has one:
+// the next stub queued for its method if there is one, then the delegate's
+// response for a spy, then the next step of a script, then a matching
+// expectation in unordered mode, then the default for the method if no
+// assertion is waiting for it. Otherwise answered is false, and the call is
+// left to the assertion side.
+func (c *FakeClient) answer(call fakeCall) (resp Call, answered bool, err error) {
+	method := call.method()
+
+	c.mu.Lock()
+	c.counts[method]++
+	if queued := c.stubs[method]; len(queued) > 0 {
+		c.stubs[method] = queued[1:]
+		c.mu.Unlock()
+		return queued[0], true, nil
+	}
+	if c.delegate != nil {
+		c.mu.Unlock()
+		return call.forward(c.delegate), true, nil
+	}
+	if c.scripted {
+		resp, err := c.next(call)
+		c.mu.Unlock()
+		return resp, true, err
+	}
+	if c.unordered {
+		resp, ok := c.match(method, call)
+		c.mu.Unlock()
+		if !ok {
+			c.t.Errorf("unexpected call %s", call)
+			return nil, true, errUnexpectedCall
+		}
+		return resp, true, nil
+	}
+	if resp, ok := c.defaults[method]; ok && !c.awaited(method) {
+		c.mu.Unlock()
+		return resp, true, nil
+	}
+	c.mu.Unlock()
+	return nil, false, nil
+}
+
+type allCall struct{ ctx context.Context }
+type allResp struct {
+	notes []*Note
+	err   error
+}
+
+func (*allCall) method() string   { return "All" }
+func (c *allCall) String() string { return "All()" }
+
+func (c *allCall) forward(store API) Call {
+	notes, err := store.All(c.ctx)
+	return &allResp{notes, err}
+}
+
+func (c *FakeClient) All(ctx context.Context) ([]*Note, error) {
+	resp, err := c.call(ctx, &allCall{ctx})
+	if err != nil {
+		return nil, err
+	}
+	r := resp.(*allResp)
+	return r.notes, r.err
+}
+
+func (c *FakeClient) AssertAll(notes []*Note, err error) context.Context {
+	call, ok := c.expect("All").(*allCall)
+	if !ok {
+		c.t.Fatal("expected an All call")
+	}
+	c.reply("All", &allResp{notes, err})
+	return call.ctx
+}
+
+// WaitForAll waits up to timeout for an All call, for code making it from
+// a goroutine of its own, and returns the function that must then answer
+// it. The call stays blocked until it is answered.
+func (c *FakeClient) WaitForAll(timeout time.Duration) func(notes []*Note, err error) {
+	if _, ok := c.expectWithin("All", timeout).(*allCall); !ok {
+		c.t.Fatal("expected an All call")
+	}
+	return func(notes []*Note, err error) {
+		c.reply("All", &allResp{notes, err})
+	}
+}
+
+type allPageCall struct {
+	ctx           context.Context
+	limit, offset int
+}
+type allPageResp struct {
+	notes []*Note
+	total int
+	err   error
+}
+
+func (*allPageCall) method() string   { return "AllPage" }
+func (c *allPageCall) String() string { return fmt.Sprintf("AllPage(%d, %d)", c.limit, c.offset) }
+
+func (c *allPageCall) forward(store API) Call {
+	pager, ok := store.(Pager)
+	if !ok {
+		return &allPageResp{err: fmt.Errorf("fake: %T is not a Pager", store)}
+	}
+	notes, total, err := pager.AllPage(c.ctx, c.limit, c.offset)
+	return &allPageResp{notes, total, err}
+}
+
+func (c *FakeClient) AllPage(ctx context.Context, limit, offset int) ([]*Note, int, error) {
+	resp, err := c.call(ctx, &allPageCall{ctx, limit, offset})
+	if err != nil {
+		return nil, 0, err
+	}
+	r := resp.(*allPageResp)
+	return r.notes, r.total, r.err
+}
+
+func (c *FakeClient) AssertAllPage(limit, offset int, notes []*Note, total int, err error) context.Context {
+	call, ok := c.expect("AllPage").(*allPageCall)
+	if !ok {
+		c.t.Fatal("expected an AllPage call")
+	}
+	if call.limit != limit || call.offset != offset {
+		c.t.Errorf("expected page with limit %d offset %d but was limit %d offset %d", limit, offset, call.limit, call.offset)
+	}
+	c.reply("AllPage", &allPageResp{notes, total, err})
+	return call.ctx
+}
+
+type getCall struct {
+	ctx  context.Context
+	text string
+}
+type getResp struct {
+	note *Note
+	err  error
+}
+
+func (*getCall) method() string   { return "Get" }
+func (c *getCall) String() string { return fmt.Sprintf("Get(%q)", c.text) }
+
+func (c *getCall) forward(store API) Call {
+	n, err := store.Get(c.ctx, c.text)
+	return &getResp{n, err}
+}
+
+func (c *FakeClient) Get(ctx context.Context, text string) (*Note, error) {
+	resp, err := c.call(ctx, &getCall{ctx, text})
+	if err != nil {
+		return nil, err
+	}
+	r := resp.(*getResp)
+	return r.note, r.err
+}
+
+func (c *FakeClient) AssertGet(text string, n *Note, err error) context.Context {
+	call, ok := c.expect("Get").(*getCall)
+	if !ok {
+		c.t.Fatal("expected a Get call")
+	}
+	if call.text != text {
+		c.t.Errorf("expected get of %q but was %q", text, call.text)
+	}
+	c.reply("Get", &getResp{n, err})
+	return call.ctx
+}
+
+type getManyCall struct {
+	ctx   context.Context
+	texts []string
+}
+type getManyResp struct {
+	notes map[string]*Note
+	err   error
+}
+
+func (*getManyCall) method() string   { return "GetMany" }
+func (c *getManyCall) String() string { return fmt.Sprintf("GetMany(%q)", c.texts) }
+
+func (c *getManyCall) forward(store API) Call {
+	notes, err := store.GetMany(c.ctx, c.texts)
+	return &getManyResp{notes, err}
+}
+
+func (c *FakeClient) GetMany(ctx context.Context, texts []string) (map[string]*Note, error) {
+	resp, err := c.call(ctx, &getManyCall{ctx, texts})
+	if err != nil {
+		return nil, err
+	}
+	r := resp.(*getManyResp)
+	return r.notes, r.err
+}
+
+func (c *FakeClient) AssertGetMany(texts []string, notes map[string]*Note, err error) context.Context {
+	call, ok := c.expect("GetMany").(*getManyCall)
+	if !ok {
+		c.t.Fatal("expected a GetMany call")
+	}
+	if !reflect.DeepEqual(call.texts, texts) {
+		c.t.Errorf("expected get many of %q but was %q", texts, call.texts)
+	}
+	c.reply("GetMany", &getManyResp{notes, err})
+	return call.ctx
+}
+
+type countCall struct{ ctx context.Context }
+type countResp struct {
+	n   int
+	err error
+}
+
+func (*countCall) method() string   { return "Count" }
+func (c *countCall) String() string { return "Count()" }
+
+func (c *countCall) forward(store API) Call {
+	n, err := countNotes(c.ctx, store)
+	return &countResp{n, err}
+}
+
+func (c *FakeClient) Count(ctx context.Context) (int, error) {
+	resp, err := c.call(ctx, &countCall{ctx})
+	if err != nil {
+		return 0, err
+	}
+	r := resp.(*countResp)
+	return r.n, r.err
+}
+
+func (c *FakeClient) AssertCount(n int, err error) context.Context {
+	call, ok := c.expect("Count").(*countCall)
+	if !ok {
+		c.t.Fatal("expected a Count call")
+	}
+	c.reply("Count", &countResp{n, err})
+	return call.ctx
+}
+
+type pingCall struct{ ctx context.Context }
+type pingResp struct{ err error }
+
+func (*pingCall) method() string   { return "Ping" }
+func (c *pingCall) String() string { return "Ping()" }
+
+func (c *pingCall) forward(store API) Call {
+	return &pingResp{store.Ping(c.ctx)}
+}
+
+func (c *FakeClient) Ping(ctx context.Context) error {
+	resp, err := c.call(ctx, &pingCall{ctx})
+	if err != nil {
+		return err
+	}
+	return resp.(*pingResp).err
+}
+
+func (c *FakeClient) AssertPing(err error) context.Context {
+	call, ok := c.expect("Ping").(*pingCall)
+	if !ok {
+		c.t.Fatal("expected a Ping call")
+	}
+	c.reply("Ping", &pingResp{err})
+	return call.ctx
+}
+
+type createCall struct {
+	ctx  context.Context
+	note *Note
 }
 
-func NewFakeClient(t *testing.T) *FakeClient {
-	return &FakeClient{t, make(chan Call)}
+// createResp answers a Create call. The created note is filled in from
+// note, when set, or else given id.
+type createResp struct {
+	id   string
+	note *Note
+	err  error
+}
+
+func (*createCall) method() string   { return "Create" }
+func (c *createCall) String() string { return fmt.Sprintf("Create(%+v)", *c.note) }
+
+func (c *createCall) forward(store API) Call {
+	return &createResp{err: store.Create(c.ctx, c.note)}
+}
+
+func (c *FakeClient) Create(ctx context.Context, n *Note) error {
+	resp, err := c.call(ctx, &createCall{ctx, n})
+	if err != nil {
+		return err
+	}
+	r := resp.(*createResp)
+	switch {
+	case r.err != nil:
+	case r.note != nil:
+		*n = *cloneNote(r.note)
+	case r.id != "":
+		n.ID = r.id
+	}
+	return r.err
+}
+
+func (c *FakeClient) AssertCreate(n *Note, err error) context.Context {
+	return c.AssertCreateWithID(n, "", err)
+}
+
+// AssertCreateWithID is AssertCreate for a store that assigns id to the
+// created note.
+func (c *FakeClient) AssertCreateWithID(n *Note, id string, err error) context.Context {
+	call, ok := c.expect("Create").(*createCall)
+	if !ok {
+		c.t.Fatal("expected a Create call")
+	}
+	if !call.note.Equal(n) {
+		c.t.Errorf("expected create with %+v but was %+v", n, call.note)
+	}
+	c.reply("Create", &createResp{id: id, err: err})
+	return call.ctx
+}
+
+// AssertCreateReturning is AssertCreate for a store that sends back the
+// note as it stored it, such as HTTPClient, with its ID and timestamps
+// filled in from stored.
+func (c *FakeClient) AssertCreateReturning(n, stored *Note, err error) context.Context {
+	call, ok := c.expect("Create").(*createCall)
+	if !ok {
+		c.t.Fatal("expected a Create call")
+	}
+	if !call.note.Equal(n) {
+		c.t.Errorf("expected create with %+v but was %+v", n, call.note)
+	}
+	c.reply("Create", &createResp{note: stored, err: err})
+	return call.ctx
+}
+
+// AssertCreateCancelled waits for a Create call, calls cancel while the
+// call is in flight and answers it with the call's context error, as a
+// store that aborts a cancelled request would.
+func (c *FakeClient) AssertCreateCancelled(cancel context.CancelFunc) *Note {
+	call, ok := c.expect("Create").(*createCall)
+	if !ok {
+		c.t.Fatal("expected a Create call")
+	}
+	cancel()
+	<-call.ctx.Done()
+	c.reply("Create", &createResp{err: call.ctx.Err()})
+	return call.note
+}
+
+// WaitForCreate is WaitForAll for a Create call, returning the note to be
+// created along with the function that must answer the call.
+func (c *FakeClient) WaitForCreate(timeout time.Duration) (*Note, func(err error)) {
+	call, ok := c.expectWithin("Create", timeout).(*createCall)
+	if !ok {
+		c.t.Fatal("expected a Create call")
+	}
+	return call.note, func(err error) {
+		c.reply("Create", &createResp{err: err})
+	}
+}
+
+// AssertCreateMatch is AssertCreate for a created note that match accepts,
+// for tests that don't care about every field.
+func (c *FakeClient) AssertCreateMatch(match func(*Note) bool, err error) context.Context {
+	call, ok := c.expect("Create").(*createCall)
+	if !ok {
+		c.t.Fatal("expected a Create call")
+	}
+	if !match(call.note) {
+		c.t.Errorf("expected create to match but was %+v", *call.note)
+	}
+	c.reply("Create", &createResp{err: err})
+	return call.ctx
+}
+
+// hasText returns a match function accepting notes whose text is text.
+func hasText(text string) func(*Note) bool {
+	return func(n *Note) bool { return n.Text == text }
+}
+
+type createManyCall struct {
+	ctx   context.Context
+	notes []*Note
+}
+type createManyResp struct{ err error }
+
+func (*createManyCall) method() string   { return "CreateMany" }
+func (c *createManyCall) String() string { return fmt.Sprintf("CreateMany(%s)", formatNotes(c.notes)) }
+
+func (c *createManyCall) forward(store API) Call {
+	return &createManyResp{store.CreateMany(c.ctx, c.notes)}
+}
+
+func (c *FakeClient) CreateMany(ctx context.Context, notes []*Note) error {
+	resp, err := c.call(ctx, &createManyCall{ctx, notes})
+	if err != nil {
+		return err
+	}
+	return resp.(*createManyResp).err
+}
+
+func (c *FakeClient) AssertCreateMany(notes []*Note, err error) context.Context {
+	call, ok := c.expect("CreateMany").(*createManyCall)
+	if !ok {
+		c.t.Fatal("expected a CreateMany call")
+	}
+	if !sameNotes(call.notes, notes) {
+		c.t.Errorf("expected create many with %s but was %s", formatNotes(notes), formatNotes(call.notes))
+	}
+	c.reply("CreateMany", &createManyResp{err})
+	return call.ctx
+}
+
+type updateCall struct {
+	ctx  context.Context
+	note *Note
+}
+type updateResp struct{ err error }
+
+func (*updateCall) method() string   { return "Update" }
+func (c *updateCall) String() string { return fmt.Sprintf("Update(%+v)", *c.note) }
+
+func (c *updateCall) forward(store API) Call {
+	return &updateResp{store.Update(c.ctx, c.note)}
+}
+
+func (c *FakeClient) Update(ctx context.Context, n *Note) error {
+	resp, err := c.call(ctx, &updateCall{ctx, n})
+	if err != nil {
+		return err
+	}
+	return resp.(*updateResp).err
+}
+
+func (c *FakeClient) AssertUpdate(n *Note, err error) context.Context {
+	call, ok := c.expect("Update").(*updateCall)
+	if !ok {
+		c.t.Fatal("expected an Update call")
+	}
+	if !call.note.Equal(n) {
+		c.t.Errorf("expected update with %+v but was %+v", n, call.note)
+	}
+	c.reply("Update", &updateResp{err})
+	return call.ctx
+}
+
+// AssertUpdateMatch is AssertUpdate for an updated note that match accepts.
+func (c *FakeClient) AssertUpdateMatch(match func(*Note) bool, err error) context.Context {
+	call, ok := c.expect("Update").(*updateCall)
+	if !ok {
+		c.t.Fatal("expected an Update call")
+	}
+	if !match(call.note) {
+		c.t.Errorf("expected update to match but was %+v", *call.note)
+	}
+	c.reply("Update", &updateResp{err})
+	return call.ctx
+}
+
+type updateFieldsCall struct {
+	ctx    context.Context
+	id     string
+	fields map[string]interface{}
+}
+type updateFieldsResp struct{ err error }
+
+func (*updateFieldsCall) method() string { return "UpdateFields" }
+func (c *updateFieldsCall) String() string {
+	return fmt.Sprintf("UpdateFields(%q, %v)", c.id, c.fields)
+}
+
+func (c *updateFieldsCall) forward(store API) Call {
+	return &updateFieldsResp{store.UpdateFields(c.ctx, c.id, c.fields)}
+}
+
+func (c *FakeClient) UpdateFields(ctx context.Context, id string, fields map[string]interface{}) error {
+	resp, err := c.call(ctx, &updateFieldsCall{ctx, id, fields})
+	if err != nil {
+		return err
+	}
+	return resp.(*updateFieldsResp).err
+}
+
+// AssertUpdateFields expects an UpdateFields call sending exactly fields.
+func (c *FakeClient) AssertUpdateFields(id string, fields map[string]interface{}, err error) context.Context {
+	call, ok := c.expect("UpdateFields").(*updateFieldsCall)
+	if !ok {
+		c.t.Fatal("expected an UpdateFields call")
+	}
+	if call.id != id || !reflect.DeepEqual(call.fields, fields) {
+		c.t.Errorf("expected update of %q with %v but was %q with %v", id, fields, call.id, call.fields)
+	}
+	c.reply("UpdateFields", &updateFieldsResp{err})
+	return call.ctx
+}
+
+type deleteCall struct {
+	ctx  context.Context
+	note *Note
+}
+type deleteResp struct{ err error }
+
+func (*deleteCall) method() string   { return "Delete" }
+func (c *deleteCall) String() string { return fmt.Sprintf("Delete(%+v)", *c.note) }
+
+func (c *deleteCall) forward(store API) Call {
+	return &deleteResp{store.Delete(c.ctx, c.note)}
+}
+
+func (c *FakeClient) Delete(ctx context.Context, n *Note) error {
+	resp, err := c.call(ctx, &deleteCall{ctx, n})
+	if err != nil {
+		return err
+	}
+	return resp.(*deleteResp).err
+}
+
+func (c *FakeClient) AssertDelete(n *Note, err error) context.Context {
+	call, ok := c.expect("Delete").(*deleteCall)
+	if !ok {
+		c.t.Fatal("expected a Delete call")
+	}
+	if !call.note.Equal(n) {
+		c.t.Errorf("expected delete with %+v but was %+v", n, call.note)
+	}
+	c.reply("Delete", &deleteResp{err})
+	return call.ctx
+}
+
+type deleteManyCall struct {
+	ctx   context.Context
+	notes []*Note
+}
+type deleteManyResp struct{ err error }
+
+func (*deleteManyCall) method() string   { return "DeleteMany" }
+func (c *deleteManyCall) String() string { return fmt.Sprintf("DeleteMany(%s)", formatNotes(c.notes)) }
+
+func (c *deleteManyCall) forward(store API) Call {
+	return &deleteManyResp{store.DeleteMany(c.ctx, c.notes)}
+}
+
+func (c *FakeClient) DeleteMany(ctx context.Context, notes []*Note) error {
+	resp, err := c.call(ctx, &deleteManyCall{ctx, notes})
+	if err != nil {
+		return err
+	}
+	return resp.(*deleteManyResp).err
+}
+
+func (c *FakeClient) AssertDeleteMany(notes []*Note, err error) context.Context {
+	call, ok := c.expect("DeleteMany").(*deleteManyCall)
+	if !ok {
+		c.t.Fatal("expected a DeleteMany call")
+	}
+	if !sameNotes(call.notes, notes) {
+		c.t.Errorf("expected delete many with %s but was %s", formatNotes(notes), formatNotes(call.notes))
+	}
+	c.reply("DeleteMany", &deleteManyResp{err})
+	return call.ctx
+}
+
+type replaceAllCall struct {
+	ctx   context.Context
+	notes []*Note
+}
+type replaceAllResp struct{ err error }
+
+func (*replaceAllCall) method() string   { return "ReplaceAll" }
+func (c *replaceAllCall) String() string { return fmt.Sprintf("ReplaceAll(%s)", formatNotes(c.notes)) }
+
+func (c *replaceAllCall) forward(store API) Call {
+	return &replaceAllResp{store.ReplaceAll(c.ctx, c.notes)}
+}
+
+func (c *FakeClient) ReplaceAll(ctx context.Context, notes []*Note) error {
+	resp, err := c.call(ctx, &replaceAllCall{ctx, notes})
+	if err != nil {
+		return err
+	}
+	return resp.(*replaceAllResp).err
+}
+
+func (c *FakeClient) AssertReplaceAll(notes []*Note, err error) context.Context {
+	call, ok := c.expect("ReplaceAll").(*replaceAllCall)
+	if !ok {
+		c.t.Fatal("expected a ReplaceAll call")
+	}
+	if !sameNotes(call.notes, notes) {
+		c.t.Errorf("expected replace all with %s but was %s", formatNotes(notes), formatNotes(call.notes))
+	}
+	c.reply("ReplaceAll", &replaceAllResp{err})
+	return call.ctx
+}
+
+type deleteAllCall struct{ ctx context.Context }
+type deleteAllResp struct{ err error }
+
+func (*deleteAllCall) method() string   { return "DeleteAll" }
+func (c *deleteAllCall) String() string { return "DeleteAll()" }
+
+func (c *deleteAllCall) forward(store API) Call {
+	clearer, ok := store.(Clearer)
+	if !ok {
+		return &deleteAllResp{fmt.Errorf("fake: %T is not a Clearer", store)}
+	}
+	return &deleteAllResp{clearer.DeleteAll(c.ctx)}
+}
+
+func (c *FakeClient) DeleteAll(ctx context.Context) error {
+	resp, err := c.call(ctx, &deleteAllCall{ctx})
+	if err != nil {
+		return err
+	}
+	return resp.(*deleteAllResp).err
+}
+
+func (c *FakeClient) AssertDeleteAll(err error) context.Context {
+	call, ok := c.expect("DeleteAll").(*deleteAllCall)
+	if !ok {
+		c.t.Fatal("expected a DeleteAll call")
+	}
+	c.reply("DeleteAll", &deleteAllResp{err})
+	return call.ctx
+}
+
+// AssertDeleteMatch is AssertDelete for a deleted note that match accepts.
+func (c *FakeClient) AssertDeleteMatch(match func(*Note) bool, err error) context.Context {
+	call, ok := c.expect("Delete").(*deleteCall)
+	if !ok {
+		c.t.Fatal("expected a Delete call")
+	}
+	if !match(call.note) {
+		c.t.Errorf("expected delete to match but was %+v", *call.note)
+	}
+	c.reply("Delete", &deleteResp{err})
+	return call.ctx
+}
+
+func sameNotes(a, b []*Note) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Notes builds a note for each of texts, with IDs counting from 1 and a
+// quantity of 1, for seeding AssertAll and the like.
+func Notes(texts ...string) []*Note {
+	notes := make([]*Note, len(texts))
+	for i, text := range texts {
+		notes[i] = &Note{ID: strconv.Itoa(i + 1), Text: text, Quantity: 1}
+	}
+	return notes
+}
+
+func formatNotes(notes []*Note) string {
+	parts := make([]string, len(notes))
+	for i, n := range notes {
+		parts[i] = fmt.Sprintf("%+v", *n)
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+// errorRecorder is a testing.TB that records errors instead of failing.
+type errorRecorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *errorRecorder) Error(args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprint(args...))
+}
+
+func (r *errorRecorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// fatalRecorder is a testing.TB that records the first fatal failure and
+// stops the goroutine that reported it, like a real test would.
+type fatalRecorder struct {
+	testing.TB
+	once  sync.Once
+	fatal chan string
+}
+
+func (r *fatalRecorder) Fatalf(format string, args ...interface{}) {
+	r.once.Do(func() { r.fatal <- fmt.Sprintf(format, args...) })
+	runtime.Goexit()
+}
+
+func (r *fatalRecorder) Fatal(args ...interface{}) {
+	r.Fatalf("%s", fmt.Sprint(args...))
+}
+
+func TestFakeClientAssertTimesOut(t *testing.T) {
+	rec := &fatalRecorder{TB: t, fatal: make(chan string, 1)}
+	client := NewFakeClientWithTimeout(rec, 20*time.Millisecond)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll(nil, nil)
+		client.AssertCreate(&Note{Text: "apples", Quantity: 1}, nil)
+	}()
+	// The list never gets as far as creating apples.
+	list.Items(context.Background())
+
+	select {
+	case msg := <-rec.fatal:
+		if want := "expected a Create call but none arrived within 20ms"; msg != want {
+			t.Errorf("expected %q but was %q", want, msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected AssertCreate to fail fast")
+	}
+}
+
+func TestNoteEqual(t *testing.T) {
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	now := time.Now()
+	tests := []struct {
+		a, b  *Note
+		equal bool
+	}{
+		{&Note{Text: "apples"}, &Note{Text: "apples"}, true},
+		{&Note{Text: "apples"}, &Note{Text: " apples "}, true},
+		{&Note{Text: "apples"}, &Note{Text: "Apples"}, false},
+		{&Note{Text: "apples", Quantity: 1}, &Note{Text: "apples", Quantity: 2}, false},
+		{&Note{Text: "apples", Archived: true}, &Note{Text: "apples"}, false},
+		{&Note{CreatedAt: created}, &Note{CreatedAt: created.In(ny)}, true},
+		{&Note{CreatedAt: created}, &Note{CreatedAt: created.Add(time.Nanosecond)}, false},
+		{&Note{UpdatedAt: now}, &Note{UpdatedAt: now.Round(0)}, true},
+		{&Note{Meta: map[string]string{"brand": "Acme"}}, &Note{Meta: map[string]string{"brand": "Acme"}}, true},
+		{&Note{Meta: map[string]string{"brand": "Acme"}}, &Note{Meta: map[string]string{"brand": "Zest"}}, false},
+		{&Note{Meta: map[string]string{}}, &Note{}, true},
+		{nil, nil, true},
+		{&Note{}, nil, false},
+		{nil, &Note{}, false},
+	}
+	for _, test := range tests {
+		if got := test.a.Equal(test.b); got != test.equal {
+			t.Errorf("expected %+v equal to %+v to be %v", test.a, test.b, test.equal)
+		}
+	}
+}
+
+func TestNoteValidate(t *testing.T) {
+	tests := []struct {
+		note *Note
+		errs []error
+	}{
+		{&Note{Text: "apples", Quantity: 1}, nil},
+		{&Note{Text: "apples"}, nil},
+		{&Note{Text: "apples", Quantity: 2, Unit: "lb"}, nil},
+		{&Note{Text: " ", Quantity: 1}, []error{ErrEmptyItem}},
+		{&Note{Text: "apples", Quantity: -1}, []error{ErrNegativeQuantity}},
+		{&Note{Text: "apples", Unit: "lb"}, []error{ErrUnitWithoutQuantity}},
+		{&Note{Quantity: -2, Unit: "lb"}, []error{ErrEmptyItem, ErrNegativeQuantity}},
+		{&Note{Unit: "lb"}, []error{ErrEmptyItem, ErrUnitWithoutQuantity}},
+	}
+	all := []error{ErrEmptyItem, ErrNegativeQuantity, ErrUnitWithoutQuantity}
+	for _, test := range tests {
+		err := test.note.Validate()
+		if (err == nil) != (len(test.errs) == 0) {
+			t.Errorf("expected %+v to fail with %v but was %v", *test.note, test.errs, err)
+			continue
+		}
+		for _, sentinel := range all {
+			want := false
+			for _, e := range test.errs {
+				want = want || e == sentinel
+			}
+			if errors.Is(err, sentinel) != want {
+				t.Errorf("expected %+v matching %v to be %v but was %v", *test.note, sentinel, want, err)
+			}
+		}
+	}
+}
+
+func TestGroceryListAddItemValidates(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+	list.MaxLength = 3
+
+	err := list.AddItemWithUnit(context.Background(), "apples", -1, "lb")
+	if !errors.Is(err, ErrNegativeQuantity) || !errors.Is(err, ErrItemTooLong) {
+		t.Error("expected ErrNegativeQuantity and ErrItemTooLong but was", err)
+	}
+	client.AssertCallCount(0)
+}
+
+func TestGroceryListMaxItems(t *testing.T) {
+	ctx := context.Background()
+	list := New()
+	list.Store = NewMemoryStore(&Note{Text: "apples", Quantity: 1})
+	list.MaxItems = 2
+
+	if err := list.AddItem(ctx, "milk"); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.AddItem(ctx, "apples"); err != nil {
+		t.Error("expected a duplicate to be skipped on a full list but was", err)
+	}
+	if err := list.AddItem(ctx, "bread"); !errors.Is(err, ErrListFull) {
+		t.Error("expected ErrListFull but was", err)
+	}
+	if err := list.AddItems(ctx, []string{"milk", "eggs"}); !errors.Is(err, ErrListFull) {
+		t.Error("expected ErrListFull from AddItems but was", err)
+	}
+
+	list.MaxItems = 0
+	if err := list.AddItems(ctx, []string{"bread", "eggs"}); err != nil {
+		t.Fatal("expected no limit but was", err)
+	}
+	if n, _ := list.Count(ctx); n != 4 {
+		t.Error("expected 4 items but was", n)
+	}
+}
+
+func TestGroceryListMaxItemsCounts(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+	list.AllowDuplicates = true
+	list.MaxItems = 3
+
+	go func() {
+		client.AssertCount(3, nil)
+		client.Close()
+	}()
+	if err := list.AddItem(context.Background(), "apples"); !errors.Is(err, ErrListFull) {
+		t.Error("expected ErrListFull but was", err)
+	}
+	client.AssertDone()
+}
+
+func TestFakeClientPeekCall(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+	client.DefaultAll([]*Note{{ID: "1", Text: "milk"}}, nil)
+	client.DefaultCreate(nil)
+
+	if err := list.AddItem(context.Background(), "apples"); err != nil {
+		t.Fatal(err)
+	}
+	var methods []string
+	for {
+		call, ok := client.PeekCall()
+		if !ok {
+			break
+		}
+		if again, _ := client.PeekCall(); again != call {
+			t.Fatal("expected peeking twice to give the same call")
+		}
+		switch call := call.(type) {
+		case *allCall:
+		case *createCall:
+			if !call.note.Equal(&Note{Text: "apples", Quantity: 1}) {
+				t.Errorf("expected a create of apples but was %+v", call.note)
+			}
+		default:
+			t.Fatalf("unexpected call %v", call)
+		}
+		next, _ := client.NextCall()
+		methods = append(methods, next.(fakeCall).method())
+	}
+	if strings.Join(methods, ",") != "All,Create" {
+		t.Error("expected All then Create but was", methods)
+	}
+}
+
+func TestFakeClientPeekCallSkipsBlockingCalls(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		if _, ok := client.PeekCall(); ok {
+			t.Error("expected no call to peek while it waits for an assertion")
+		}
+		client.AssertAll(nil, nil)
+		client.Close()
+	}()
+	if _, err := list.Items(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+}
+
+func TestFakeClientStubItems(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	client.StubItems("apples", "bread", "cheese", "dates", "eggs", "flour", "grapes", "ham", "ice", "jam")
+	items, err := list.Items(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 10 || items[0] != "apples" || items[9] != "jam" {
+		t.Fatal("expected the ten stubbed items but was", items)
+	}
+	ok, err := list.Contains(context.Background(), "ham")
+	if err != nil || !ok {
+		t.Error("expected the stubbed ham to be on the list but was", ok, err)
+	}
+
+	client = NewFakeClient(t)
+	list.Store = client
+	go func() {
+		client.AssertAll(Notes("milk", "tea"), nil)
+		client.Close()
+	}()
+	if items, _ := list.Items(context.Background()); len(items) != 2 || items[1] != "tea" {
+		t.Error("expected milk and tea but was", items)
+	}
+	client.AssertDone()
+}
+
+func TestFakeClientAssertNoCalls(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	if err := list.AddItem(context.Background(), "  "); !errors.Is(err, ErrEmptyItem) {
+		t.Fatal("expected ErrEmptyItem but was", err)
+	}
+	client.Close()
+	client.AssertDone()
+	client.AssertNoCalls()
+
+	rec := &errorRecorder{TB: t}
+	client = NewFakeClient(rec)
+	list.Store = client
+	client.StubAll(nil, nil)
+	client.StubCreate(nil)
+	if err := list.AddItem(context.Background(), "apples"); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertNoCalls()
+	if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "All()") || !strings.Contains(rec.errors[0], "Create(") {
+		t.Errorf("expected the All and Create calls to be listed but was %q", rec.errors)
+	}
+}
+
+func TestFakeClientWaitFor(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	done := make(chan error, 1)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		done <- list.AddItem(context.Background(), "apples")
+	}()
+
+	client.WaitForAll(time.Second)(nil, nil)
+	note, reply := client.WaitForCreate(time.Second)
+	if note.Text != "apples" {
+		t.Errorf("expected apples to be created but was %+v", *note)
+	}
+	reply(nil)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+	client.AssertDone()
+}
+
+func TestFakeClientRecordMode(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	client.StubAll(nil, nil)
+	client.StubCreate(nil)
+	client.StubAll([]*Note{{Text: "apples", Quantity: 1}}, nil)
+
+	if err := list.AddItem(context.Background(), "apples"); err != nil {
+		t.Fatal(err)
+	}
+	items, err := list.Items(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0] != "apples" {
+		t.Fatal("expected apples but was", items)
+	}
+
+	calls := client.RecordedCalls()
+	if len(calls) != 3 {
+		t.Fatal("expected three calls but was", len(calls))
+	}
+	if _, ok := calls[0].(*allCall); !ok {
+		t.Fatalf("expected an all call first but was %T", calls[0])
+	}
+	create, ok := calls[1].(*createCall)
+	if !ok {
+		t.Fatalf("expected a create call second but was %T", calls[1])
+	}
+	if !create.note.Equal(&Note{Text: "apples", Quantity: 1}) {
+		t.Errorf("expected create with 1 apples but was %+v", create.note)
+	}
+	if _, ok := calls[2].(*allCall); !ok {
+		t.Fatalf("expected an all call third but was %T", calls[2])
+	}
+}
+
+func TestFakeClientAssertCallSequence(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+	list.AllowDuplicates = true
+
+	client.DefaultCreate(nil)
+	client.DefaultAll(nil, nil)
+	ctx := context.Background()
+	list.AddItem(ctx, "apples")
+	list.Items(ctx)
+	list.AddItem(ctx, "bananas")
+
+	client.AssertCallSequence(
+		createExpectation(&Note{Text: "apples", Quantity: 1}),
+		allExpectation(),
+		createExpectation(&Note{Text: "bananas", Quantity: 1}),
+	)
+
+	rec := &errorRecorder{TB: t}
+	client.t = rec
+	client.AssertCallSequence(
+		createExpectation(&Note{Text: "apples", Quantity: 1}),
+		createExpectation(&Note{Text: "bananas", Quantity: 1}),
+	)
+	if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "! 2: expected Create({") ||
+		!strings.Contains(rec.errors[0], "! 3: expected (none)") {
+		t.Errorf("expected a diff from the second call on but was %q", rec.errors)
+	}
+}
+
+func TestFakeClientUnorderedMode(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	client.ExpectAll(nil, nil)
+	client.ExpectAll(nil, nil)
+	client.ExpectCreate(&Note{Text: "milk", Quantity: 1}, nil)
+	client.ExpectCreate(&Note{Text: "apples", Quantity: 1}, nil)
+
+	var wg sync.WaitGroup
+	for _, item := range []string{"apples", "milk"} {
+		wg.Add(1)
+		go func(item string) {
+			defer wg.Done()
+			if err := list.AddItem(context.Background(), item); err != nil {
+				t.Error(err)
+			}
+		}(item)
+	}
+	wg.Wait()
+
+	client.AssertExpectationsMet()
+}
+
+func TestFakeClientScript(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	client.Expect().
+		All().Returns(nil, nil).
+		Then().Create(&Note{Text: "apples", Quantity: 1}).Returns(nil).
+		Then().All().Returns([]*Note{{Text: "apples", Quantity: 1}}, nil)
+
+	if err := list.AddItem(context.Background(), "apples"); err != nil {
+		t.Fatal(err)
+	}
+	items, err := list.Items(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0] != "apples" {
+		t.Fatal("expected apples but was", items)
+	}
+
+	client.AssertExpectationsMet()
+}
+
+func TestFakeClientAssertDoneReportsUnmetExpectations(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	client.ExpectAll(nil, nil)
+	client.ExpectCreate(&Note{Text: "apples", Quantity: 1}, nil)
+	client.ExpectCreate(&Note{Text: "milk", Quantity: 1}, nil)
+	if err := list.AddItem(context.Background(), "apples"); err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+
+	rec := &errorRecorder{TB: t}
+	client.t = rec
+	client.AssertDone()
+	if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "Text:milk") {
+		t.Errorf("expected the milk Create reported unmet but was %q", rec.errors)
+	}
+}
+
+func TestFakeClientQueueAll(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	failed := errors.New("store is on fire")
+	client.QueueAll(
+		allResp{notes: []*Note{{Text: "apples"}}},
+		allResp{err: failed},
+		allResp{notes: []*Note{{Text: "apples"}, {Text: "milk"}}},
+	)
+
+	items, err := list.Items(context.Background())
+	if err != nil || strings.Join(items, ",") != "apples" {
+		t.Fatal("expected apples but was", items, err)
+	}
+	if _, err := list.Items(context.Background()); !errors.Is(err, failed) {
+		t.Fatal("expected store is on fire but was", err)
+	}
+	items, err = list.Items(context.Background())
+	if err != nil || strings.Join(items, ",") != "apples,milk" {
+		t.Fatal("expected apples and milk but was", items, err)
+	}
+	client.AssertAllCount(3)
+
+	go func() {
+		client.AssertAll(nil, nil)
+		client.Close()
+	}()
+	if _, err := list.Items(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+}
+
+func TestFakeClientMatch(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+	list.AddMode = AddIncrementQuantity
+
+	go func() {
+		client.AssertAll(nil, nil)
+		client.AssertCreateMatch(hasText("apples"), nil)
+		client.AssertAll([]*Note{{ID: "1", Text: "apples", Quantity: 1, CreatedAt: time.Now()}}, nil)
+		client.AssertUpdateMatch(func(n *Note) bool {
+			return n.Text == "apples" && n.Quantity == 2
+		}, nil)
+		client.AssertAll([]*Note{{ID: "1", Text: "apples", Quantity: 2}}, nil)
+		client.AssertDeleteMatch(hasText("apples"), nil)
+		client.Close()
+	}()
+	for i := 0; i < 2; i++ {
+		if err := list.AddItem(context.Background(), "apples"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := list.RemoveItem(context.Background(), "apples"); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+}
+
+func TestSpyClient(t *testing.T) {
+	store := NewMemoryStore(&Note{Text: "milk", Quantity: 1})
+	client := NewSpyClient(t, store)
+	list := New()
+	list.Store = client
+
+	if err := list.AddItem(context.Background(), "apples"); err != nil {
+		t.Fatal(err)
+	}
+	items, err := list.Items(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(items, ",") != "milk,apples" {
+		t.Fatal("expected milk and apples but was", items)
+	}
+
+	calls := client.RecordedCalls()
+	responses := client.RecordedResponses()
+	if len(calls) != 3 || len(responses) != 3 {
+		t.Fatalf("expected 3 calls and responses but was %d and %d", len(calls), len(responses))
+	}
+	if create, ok := calls[1].(*createCall); !ok || create.note.Text != "apples" {
+		t.Fatalf("expected the second call to create apples but was %v", calls[1])
+	}
+	if create := responses[1].(*createResp); create.err != nil {
+		t.Error("expected the create to succeed but was", create.err)
+	}
+	if all := responses[2].(*allResp); len(all.notes) != 2 {
+		t.Errorf("expected the last All to return 2 notes but was %s", formatNotes(all.notes))
+	}
+}
+
+func TestFakeClientAssertCreateReturned(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+	list.AllowDuplicates = true
+	boom := errors.New("boom")
+
+	go func() {
+		client.AssertCreate(&Note{Text: "apples", Quantity: 1}, boom)
+		client.Close()
+	}()
+	if err := list.AddItem(context.Background(), "apples"); !errors.Is(err, boom) {
+		t.Fatal("expected boom but was", err)
+	}
+	client.AssertDone()
+	client.AssertCreateReturned(boom)
+
+	rec := &errorRecorder{TB: t}
+	client.t = rec
+	client.AssertCreateReturned(nil)
+	client.AssertCreateReturned(ErrItemNotFound)
+	if len(rec.errors) != 2 {
+		t.Fatalf("expected 2 failures but was %q", rec.errors)
+	}
+}
+
+func TestFakeClientDefaults(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	client.DefaultAll([]*Note{{Text: "apples", Quantity: 1}}, nil)
+
+	go func() {
+		client.AssertCreate(&Note{Text: "milk", Quantity: 1}, nil)
+		client.Close()
+	}()
+	if err := list.AddItem(context.Background(), "milk"); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+
+	for i := 0; i < 3; i++ {
+		items, err := list.Items(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != 1 || items[0] != "apples" {
+			t.Fatal("expected the default apples but was", items)
+		}
+	}
+}
+
+func TestFakeClientCallCounts(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll(nil, nil)
+		client.AssertCreate(&Note{Text: "apples", Quantity: 1}, nil)
+		client.AssertAll([]*Note{{Text: "apples", Quantity: 1}}, nil)
+		client.AssertCreate(&Note{Text: "milk", Quantity: 1}, nil)
+		client.Close()
+	}()
+	list.AddItem(context.Background(), "apples")
+	list.AddItem(context.Background(), "milk")
+	client.AssertDone()
+
+	client.AssertCreateCount(2)
+	client.AssertAllCount(2)
+	client.AssertCallCount(4)
+}
+
+func TestGroceryListCreate(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll(nil, nil)
+		client.AssertCreate(&Note{Text: "apples", Quantity: 1}, nil)
+		client.Close()
+	}()
+	list.AddItem(context.Background(), "apples")
+	client.AssertDone()
+}
+
+func TestGroceryListCreateSkipsDuplicates(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{{Text: "apples", Quantity: 1}}, nil)
+		client.Close()
+	}()
+	if err := list.AddItem(context.Background(), "apples"); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListCreateAllowDuplicates(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+	list.AllowDuplicates = true
+
+	go func() {
+		client.AssertCreate(&Note{Text: "apples", Quantity: 1}, nil)
+		client.Close()
+	}()
+	if err := list.AddItem(context.Background(), "apples"); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListCreateTrimsItem(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll(nil, nil)
+		client.AssertCreate(&Note{Text: "apples", Quantity: 1}, nil)
+		client.Close()
+	}()
+	if err := list.AddItem(context.Background(), "  apples\t"); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListCreateRejectsEmptyItem(t *testing.T) {
+	for _, item := range []string{"", "   "} {
+		client := NewFakeClient(t)
+		list := New()
+		list.Store = client
+
+		if err := list.AddItem(context.Background(), item); !errors.Is(err, ErrEmptyItem) {
+			t.Errorf("expected ErrEmptyItem for %q but was %v", item, err)
+		}
+		client.Close()
+		client.AssertDone()
+	}
+}
+
+func TestGroceryListCreateRejectsLongItem(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+	list.MaxLength = 5
+
+	if err := list.AddItem(context.Background(), "bananas"); !errors.Is(err, ErrItemTooLong) {
+		t.Fatal("expected ErrItemTooLong but was", err)
+	}
+	client.Close()
+	client.AssertDone()
+}
+
+func TestGroceryListDedupModes(t *testing.T) {
+	tests := []struct {
+		mode     DedupMode
+		existing string
+		creates  bool
+	}{
+		{DedupExact, "apples", false},
+		{DedupExact, "Apples", true},
+		{DedupIgnoreCase, "Apples", false},
+		// Stored items are normalized before they are compared too.
+		{DedupIgnoreCase, " Apples ", false},
+		{DedupIgnoreCaseAndSpace, " Apples ", false},
+		{DedupIgnoreCaseAndSpace, "green apples", true},
+	}
+	for _, test := range tests {
+		client := NewFakeClient(t)
+		list := New()
+		list.Store = client
+		list.DedupMode = test.mode
+
+		client.StubAll([]*Note{{Text: test.existing}}, nil)
+		client.DefaultCreate(nil)
+		if err := list.AddItem(context.Background(), "apples"); err != nil {
+			t.Fatal(err)
+		}
+
+		created := 0
+		for _, call := range client.RecordedCalls() {
+			if _, ok := call.(*createCall); ok {
+				created++
+			}
+		}
+		if test.creates && created != 1 {
+			t.Errorf("mode %d: expected apples to be added alongside %q", test.mode, test.existing)
+		}
+		if !test.creates && created != 0 {
+			t.Errorf("mode %d: expected apples to duplicate %q", test.mode, test.existing)
+		}
+	}
+}
+
+func TestGroceryListNormalizer(t *testing.T) {
+	store := NewMemoryStore()
+	list := New()
+	list.Store = store
+	ctx := context.Background()
+
+	for _, item := range []string{"green apples", "  green   apples ", "green\tapples"} {
+		if err := list.AddItem(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	notes, _ := store.All(ctx)
+	if len(notes) != 1 || notes[0].Text != "green apples" {
+		t.Fatalf("expected one note for green apples but was %s", formatNotes(notes))
+	}
+	if ok, _ := list.Contains(ctx, " green  apples"); !ok {
+		t.Error("expected to contain green  apples")
+	}
+	if items, _ := list.Search(ctx, "green  app"); len(items) != 1 {
+		t.Error("expected to find green  app but was", items)
+	}
+
+	list.Normalizer = NormalizeTitle
+	if err := list.AddItem(ctx, "  bread  rolls"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, "Bread Rolls"); err != nil {
+		t.Error("expected Bread Rolls to be stored but was", err)
+	}
+}
+
+func TestGroceryListCleanItems(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+	list.DedupMode = DedupIgnoreCase
+
+	client.StubItems("  milk", "Bread", "green   apples", "bread", "Milk", "green apples ", "cheese", "milk")
+	items, err := list.CleanItems(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Bread", "cheese", "green apples", "Milk"}; !reflect.DeepEqual(items, want) {
+		t.Errorf("expected %q but was %q", want, items)
+	}
+
+	client.StubItems()
+	items, err = list.CleanItems(context.Background())
+	if err != nil || items == nil || len(items) != 0 {
+		t.Errorf("expected an empty list but was %q, %v", items, err)
+	}
+}
+
+func TestGroceryListAddItems(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{{Text: "milk", Quantity: 1}}, nil)
+		client.AssertCreateMany([]*Note{
+			{Text: "apples", Quantity: 1},
+			{Text: "bread", Quantity: 1},
+		}, nil)
+		client.Close()
+	}()
+	if err := list.AddItems(context.Background(), []string{"apples", "milk", "bread", "apples"}); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListCreateWithQuantity(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll(nil, nil)
+		client.AssertCreate(&Note{Text: "apples", Quantity: 3}, nil)
+		client.Close()
+	}()
+	list.AddItemWithQuantity(context.Background(), "apples", 3)
+	client.AssertDone()
+}
+
+func TestGroceryListCreateInCategory(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll(nil, nil)
+		client.AssertCreate(&Note{Text: "milk", Quantity: 1, Category: "dairy"}, nil)
+		client.Close()
+	}()
+	if err := list.AddItemInCategory(context.Background(), "milk", "dairy"); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListCreateDeadline(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	go func() {
+		client.AssertAll(nil, nil)
+		callCtx := client.AssertCreate(&Note{Text: "apples", Quantity: 1}, nil)
+		if _, ok := callCtx.Deadline(); !ok {
+			t.Error("expected create to carry a deadline")
+		}
+		client.Close()
+	}()
+	list.AddItem(ctx, "apples")
+	client.AssertDone()
+}
+
+func TestGroceryListCreateCancelled(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := list.AddItem(ctx, "apples"); !errors.Is(err, context.Canceled) {
+		t.Fatal("expected context.Canceled but was", err)
+	}
+	client.Close()
+	client.AssertDone()
+}
+
+func TestGroceryListCreateCancelledInFlight(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+	list.UndoDepth = 1
+	var events []ChangeEvent
+	list.OnChange(func(e ChangeEvent) { events = append(events, e) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		client.AssertAll(nil, nil)
+		client.AssertCreateCancelled(cancel)
+		client.Close()
+	}()
+	if err := list.AddItem(ctx, "apples"); !errors.Is(err, context.Canceled) {
+		t.Fatal("expected context.Canceled but was", err)
+	}
+	client.AssertDone()
+	if len(events) != 0 {
+		t.Errorf("expected no change for the cancelled add but was %+v", events)
+	}
+	if err := list.Undo(context.Background()); !errors.Is(err, ErrNothingToUndo) {
+		t.Error("expected nothing to undo but was", err)
+	}
+}
+
+func TestFakeClientCancelledWhileWaiting(t *testing.T) {
+	client := NewFakeClient(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := client.All(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatal("expected context.Canceled but was", err)
+	}
+	if d := time.Since(start); d > defaultFakeTimeout/2 {
+		t.Fatal("expected All to return promptly on cancel but took", d)
+	}
+	client.Close()
+	client.AssertDone()
+}
+
+func TestGroceryListRemove(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{{ID: "1", Text: "milk"}, {ID: "2", Text: "apples"}}, nil)
+		client.AssertDelete(&Note{ID: "2", Text: "apples"}, nil)
+		client.Close()
+	}()
+	if err := list.RemoveItem(context.Background(), "apples"); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListCreateAssignsID(t *testing.T) {
+	client := NewFakeClient(t)
+
+	n := &Note{Text: "apples", Quantity: 1}
+	go func() {
+		client.AssertCreateWithID(&Note{Text: "apples", Quantity: 1}, "42", nil)
+		client.Close()
+	}()
+	if err := client.Create(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if n.ID != "42" {
+		t.Fatal("expected the created note to get ID 42 but was", n.ID)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListAddItemReturning(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	created := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	go func() {
+		client.AssertAll(nil, nil)
+		client.AssertCreateReturning(&Note{Text: "apples", Quantity: 1}, &Note{ID: "42", Text: "apples", Quantity: 1, CreatedAt: created}, nil)
+		client.AssertAll([]*Note{{ID: "42", Text: "apples", Quantity: 1, CreatedAt: created}}, nil)
+		client.Close()
+	}()
+	n, err := list.AddItemReturning(context.Background(), "apples")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.ID != "42" || !n.CreatedAt.Equal(created) {
+		t.Fatalf("expected the note the store returned but was %+v", n)
+	}
+	existing, err := list.AddItemReturning(context.Background(), "apples")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if existing.ID != "42" {
+		t.Fatal("expected the existing note but was", existing)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListMarkPurchased(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{{ID: "1", Text: "milk", Quantity: 1}, {ID: "2", Text: "apples", Quantity: 3}}, nil)
+		client.AssertUpdate(&Note{ID: "2", Text: "apples", Quantity: 3, Purchased: true}, nil)
+		client.Close()
+	}()
+	if err := list.MarkPurchased(context.Background(), "apples"); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListMoveItem(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	notes := []*Note{{ID: "1", Text: "milk", Quantity: 1, Category: "dairy"}, {ID: "2", Text: "apples", Quantity: 3}}
+	go func() {
+		client.AssertAll(notes, nil)
+		client.AssertUpdate(&Note{ID: "2", Text: "apples", Quantity: 3, Category: "produce"}, nil)
+		client.AssertAll(notes, nil)
+		client.AssertAll(notes, nil)
+		client.Close()
+	}()
+	if err := list.MoveItem(context.Background(), "apples", "produce"); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.MoveItem(context.Background(), "milk", "dairy"); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.MoveItem(context.Background(), "bread", "bakery"); !errors.Is(err, ErrItemNotFound) {
+		t.Fatal("expected ErrItemNotFound but was", err)
+	}
+	client.AssertDone()
+	client.AssertCallCount(4)
+}
+
+func TestGroceryListMarkPurchasedMissing(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{{Text: "milk", Quantity: 1}}, nil)
+		client.Close()
+	}()
+	if err := list.MarkPurchased(context.Background(), "apples"); !errors.Is(err, ErrItemNotFound) {
+		t.Fatal("expected ErrItemNotFound but was", err)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListSetQuantity(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{{ID: "4", Text: "apples", Quantity: 1, Category: "produce"}}, nil)
+		client.AssertUpdateFields("4", map[string]interface{}{"quantity": 6}, nil)
+		client.Close()
+	}()
+	if err := list.SetQuantity(context.Background(), "apples", 6); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListSetMeta(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{{ID: "4", Text: "apples", Quantity: 1, Meta: map[string]string{"brand": "Acme"}}}, nil)
+		client.AssertUpdateFields("4", map[string]interface{}{"meta": map[string]string{"brand": "Acme", "url": "example.com"}}, nil)
+		client.Close()
+	}()
+	if err := list.SetMeta(context.Background(), "apples", "url", "example.com"); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListGetMeta(t *testing.T) {
+	ctx := context.Background()
+	list := New()
+	list.Store = NewMemoryStore(&Note{Text: "apples", Quantity: 1})
+
+	if err := list.SetMeta(ctx, "apples", "brand", "Acme"); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.SetMeta(ctx, "apples", "aisle", "4"); err != nil {
+		t.Fatal(err)
+	}
+	if brand, ok, err := list.GetMeta(ctx, "apples", "brand"); err != nil || !ok || brand != "Acme" {
+		t.Errorf("expected brand Acme but was %q, %v, %v", brand, ok, err)
+	}
+	if aisle, ok, _ := list.GetMeta(ctx, "apples", "aisle"); !ok || aisle != "4" {
+		t.Errorf("expected aisle 4 but was %q, %v", aisle, ok)
+	}
+	if _, ok, _ := list.GetMeta(ctx, "apples", "url"); ok {
+		t.Error("expected no url")
+	}
+	if _, _, err := list.GetMeta(ctx, "milk", "brand"); !errors.Is(err, ErrItemNotFound) {
+		t.Error("expected ErrItemNotFound but was", err)
+	}
+}
+
+func TestGroceryListSetItems(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+	var ops []ChangeOp
+	list.OnChange(func(e ChangeEvent) { ops = append(ops, e.Op) })
+
+	go func() {
+		client.AssertReplaceAll([]*Note{{Text: "apples", Quantity: 1}, {Text: "milk", Quantity: 1}}, nil)
+		client.Close()
+	}()
+	if err := list.SetItems(context.Background(), []string{" apples", "milk", "apples"}); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+	if want := []ChangeOp{ChangeClear, ChangeAdd, ChangeAdd}; !reflect.DeepEqual(ops, want) {
+		t.Errorf("expected %v but was %v", want, ops)
+	}
+}
+
+func TestGroceryListSetItemsEmptyClears(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(&Note{Text: "apples", Quantity: 1}, &Note{Text: "milk", Quantity: 1})
+	client := NewSpyClient(t, store)
+	list := New()
+	list.Store = client
+
+	if err := list.SetItems(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertCallSequence(replaceAllExpectation(nil))
+	if n, _ := store.Count(ctx); n != 0 {
+		t.Error("expected the list cleared but it has", n, "items")
+	}
+}
+
+func TestGroceryListClearPurchased(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{
+			{ID: "1", Text: "milk", Purchased: true},
+			{ID: "2", Text: "apples"},
+			{ID: "3", Text: "bread", Purchased: true},
+			{ID: "4", Text: "eggs", Purchased: true},
+		}, nil)
+		client.AssertDeleteMany([]*Note{
+			{ID: "1", Text: "milk", Purchased: true},
+			{ID: "3", Text: "bread", Purchased: true},
+			{ID: "4", Text: "eggs", Purchased: true},
+		}, &BatchError{Failures: []BatchFailure{{Index: 1, Message: "store is on fire"}}})
+		client.Close()
+	}()
+	n, err := list.ClearPurchased(context.Background())
+	if n != 2 {
+		t.Error("expected 2 removed but was", n)
+	}
+	if err == nil || !strings.Contains(err.Error(), `"bread": store is on fire`) {
+		t.Fatal("expected the error to name bread but was", err)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListClearAll(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertDeleteAll(nil)
+		client.Close()
+	}()
+	if err := list.ClearAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListClearAllEmpty(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	// Hide DeleteAll so the notes are fetched and deleted.
+	list.Store = struct{ API }{client}
+
+	go func() {
+		client.AssertAll(nil, nil)
+		client.Close()
+	}()
+	if err := list.ClearAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListClearAllPartialFailure(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = struct{ API }{client}
+
+	notes := []*Note{{ID: "1", Text: "milk"}, {ID: "2", Text: "apples"}, {ID: "3", Text: "bread"}}
+	go func() {
+		client.AssertAll(notes, nil)
+		client.AssertDeleteMany(notes, &BatchError{Failures: []BatchFailure{{Index: 1, Message: "locked"}}})
+		client.Close()
+	}()
+	err := list.ClearAll(context.Background())
+	if err == nil || !strings.Contains(err.Error(), `"apples": locked`) {
+		t.Fatal("expected the error to name apples but was", err)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListRecentItems(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	go func() {
+		client.AssertAll([]*Note{
+			{Text: "milk", CreatedAt: day},
+			{Text: "apples", CreatedAt: day.Add(2 * time.Hour)},
+			{Text: "bread", CreatedAt: day.Add(time.Hour)},
+		}, nil)
+		client.Close()
+	}()
+	items, err := list.RecentItems(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(items, ",") != "apples,bread" {
+		t.Fatal("expected apples and bread but was", items)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListTouch(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	list := New()
+	list.Store = NewMemoryStore(
+		&Note{Text: "milk", CreatedAt: day},
+		&Note{Text: "apples", CreatedAt: day.Add(2 * time.Hour)},
+		&Note{Text: "bread", CreatedAt: day.Add(time.Hour)},
+	)
+	ctx := context.Background()
+
+	if items, _ := list.RecentItems(ctx, 3); strings.Join(items, ",") != "apples,bread,milk" {
+		t.Fatal("expected apples, bread and milk but was", items)
+	}
+	if err := list.Touch(ctx, "milk"); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := list.RecentItems(ctx, 3); strings.Join(items, ",") != "milk,apples,bread" {
+		t.Error("expected the touched milk first but was", items)
+	}
+	milk, err := list.GetItem(ctx, "milk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if milk.Text != "milk" || !milk.CreatedAt.Equal(day) {
+		t.Errorf("expected touching to leave milk as it was but was %+v", *milk)
+	}
+	if err := list.Touch(ctx, "eggs"); !errors.Is(err, ErrItemNotFound) {
+		t.Error("expected ErrItemNotFound but was", err)
+	}
+}
+
+func TestGroceryListOnChange(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	var order []string
+	var events []ChangeEvent
+	list.OnChange(func(e ChangeEvent) {
+		order = append(order, "first")
+		events = append(events, e)
+	})
+	list.OnChange(func(ChangeEvent) { order = append(order, "second") })
+
+	go func() {
+		client.AssertAll(nil, nil)
+		client.AssertCreateWithID(&Note{Text: "apples", Quantity: 1}, "7", nil)
+		client.Close()
+	}()
+	if err := list.AddItem(context.Background(), "apples"); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+
+	if len(events) != 1 {
+		t.Fatal("expected 1 event but was", len(events))
+	}
+	if e := events[0]; e.Op != ChangeAdd || !e.Note.Equal(&Note{ID: "7", Text: "apples", Quantity: 1}) {
+		t.Errorf("expected an add of apples with ID 7 but was %v %+v", e.Op, e.Note)
+	}
+	if strings.Join(order, ",") != "first,second" {
+		t.Error("expected observers in registration order but was", order)
+	}
+}
+
+func TestGroceryListSubscribe(t *testing.T) {
+	ctx := context.Background()
+	list := New()
+	list.Store = NewMemoryStore()
+
+	dropping, unsubscribeDropping := list.Subscribe(1, OverflowDrop)
+	buffered, unsubscribeBuffered := list.Subscribe(0, OverflowBuffer)
+	for _, item := range []string{"apples", "milk"} {
+		if err := list.AddItem(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if e := <-dropping; e.Op != ChangeAdd || e.Note.Text != "apples" {
+		t.Errorf("expected an add of apples but was %v %+v", e.Op, e.Note)
+	}
+	for _, want := range []string{"apples", "milk"} {
+		select {
+		case e := <-buffered:
+			if e.Op != ChangeAdd || e.Note.Text != want {
+				t.Errorf("expected an add of %s but was %v %+v", want, e.Op, e.Note)
+			}
+		case <-time.After(defaultFakeTimeout):
+			t.Fatal("expected an add of", want)
+		}
+	}
+
+	unsubscribeDropping()
+	unsubscribeDropping()
+	if e, ok := <-dropping; ok {
+		t.Errorf("expected the milk add dropped and the channel closed but got %+v", e.Note)
+	}
+	if err := list.AddItem(ctx, "bread"); err != nil {
+		t.Fatal(err)
+	}
+	if e := <-buffered; e.Note.Text != "bread" {
+		t.Error("expected an add of bread but was", e.Note)
+	}
+
+	unsubscribeBuffered()
+	select {
+	case _, ok := <-buffered:
+		if ok {
+			t.Error("expected no events after unsubscribing")
+		}
+	case <-time.After(defaultFakeTimeout):
+		t.Fatal("expected the channel closed after unsubscribing")
+	}
+	if len(list.subscribers) != 0 {
+		t.Error("expected no subscribers left but there were", len(list.subscribers))
+	}
+}
+
+func TestGroceryListOnChangeNotFiredOnError(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	list.OnChange(func(e ChangeEvent) {
+		t.Errorf("expected no event but got %v %+v", e.Op, e.Note)
+	})
+
+	go func() {
+		client.AssertAll(nil, nil)
+		client.AssertCreate(&Note{Text: "apples", Quantity: 1}, errors.New("store is on fire"))
+		client.Close()
+	}()
+	if err := list.AddItem(context.Background(), "apples"); err == nil {
+		t.Fatal("expected an error")
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListOnChangeCanUseList(t *testing.T) {
+	list := New()
+	list.Store = &MemoryStore{}
+
+	var items []string
+	list.OnChange(func(ChangeEvent) {
+		var err error
+		if items, err = list.Items(context.Background()); err != nil {
+			t.Error(err)
+		}
+	})
+	if err := list.AddItem(context.Background(), "apples"); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0] != "apples" {
+		t.Fatal("expected the observer to see apples but was", items)
+	}
+}
+
+func TestGroceryListMergeFrom(t *testing.T) {
+	mine := NewFakeClient(t)
+	list := New()
+	list.Store = mine
+	theirs := NewFakeClient(t)
+	other := New()
+	other.Store = theirs
+
+	go func() {
+		theirs.AssertAll([]*Note{{ID: "1", Text: "apples", Quantity: 2}, {ID: "2", Text: "eggs", Quantity: 12}, {ID: "3", Text: "Milk"}}, nil)
+		theirs.Close()
+	}()
+	go func() {
+		mine.AssertAll([]*Note{{ID: "7", Text: "milk"}, {ID: "8", Text: "apples"}}, nil)
+		mine.AssertCreateMany([]*Note{{Text: "eggs", Quantity: 12}}, nil)
+		mine.Close()
+	}()
+	list.DedupMode = DedupIgnoreCase
+	added, err := list.MergeFrom(context.Background(), other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 1 {
+		t.Fatal("expected 1 item added but was", added)
+	}
+
+	theirs.AssertDone()
+	mine.AssertDone()
+}
+
+func TestGroceryListMergeModes(t *testing.T) {
+	ours := []*Note{{ID: "7", Text: "apples", Quantity: 2}, {ID: "8", Text: "flour", Quantity: 1, Unit: "kg"}}
+	theirs := []*Note{{ID: "1", Text: "apples", Quantity: 3}, {ID: "2", Text: "flour", Quantity: 500, Unit: "g"}, {ID: "3", Text: "eggs", Quantity: 6}, {ID: "4", Text: "eggs", Quantity: 6}}
+	tests := []struct {
+		mode   MergeMode
+		merged int
+		assert func(client *FakeClient)
+	}{
+		{MergeSkipDuplicates, 1, func(client *FakeClient) {
+			client.AssertCreateMany([]*Note{{Text: "eggs", Quantity: 6}}, nil)
+		}},
+		{MergeSumQuantities, 2, func(client *FakeClient) {
+			client.AssertCreateMany([]*Note{{Text: "eggs", Quantity: 12}}, nil)
+			client.AssertUpdate(&Note{ID: "7", Text: "apples", Quantity: 5}, nil)
+		}},
+		{MergeMaxQuantity, 2, func(client *FakeClient) {
+			client.AssertCreateMany([]*Note{{Text: "eggs", Quantity: 6}}, nil)
+			client.AssertUpdate(&Note{ID: "7", Text: "apples", Quantity: 3}, nil)
+		}},
+	}
+	for _, tt := range tests {
+		mine := NewFakeClient(t)
+		list := New()
+		list.Store = mine
+		list.MergeMode = tt.mode
+		other := New()
+		other.Store = NewMemoryStore(cloneNotes(theirs)...)
+
+		go func() {
+			mine.AssertAll(cloneNotes(ours), nil)
+			tt.assert(mine)
+			mine.Close()
+		}()
+		merged, err := list.MergeFrom(context.Background(), other)
+		if tt.mode == MergeSkipDuplicates {
+			if err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			var mismatch *UnitMismatchError
+			if !errors.As(err, &mismatch) || !errors.Is(err, ErrUnitMismatch) {
+				t.Fatalf("mode %d: expected a UnitMismatchError but was %v", tt.mode, err)
+			}
+			if len(mismatch.Items) != 1 || mismatch.Items[0] != "flour" {
+				t.Fatalf("mode %d: expected flour reported but was %v", tt.mode, mismatch.Items)
+			}
+		}
+		if merged != tt.merged {
+			t.Fatalf("mode %d: expected %d items merged but was %d", tt.mode, tt.merged, merged)
+		}
+		mine.AssertDone()
+	}
+}
+
+func TestGroceryListAddModes(t *testing.T) {
+	existing := []*Note{{ID: "1", Text: "apples", Quantity: 2}}
+	tests := []struct {
+		mode   AddMode
+		assert func(client *FakeClient)
+	}{
+		{AddSkipDuplicates, func(client *FakeClient) {
+			client.AssertAll(existing, nil)
+		}},
+		{AddIncrementQuantity, func(client *FakeClient) {
+			client.AssertAll(existing, nil)
+			client.AssertUpdate(&Note{ID: "1", Text: "apples", Quantity: 3}, nil)
+		}},
+		{AddAllowDuplicates, func(client *FakeClient) {
+			client.AssertCreate(&Note{Text: "apples", Quantity: 1}, nil)
+		}},
+	}
+	for _, test := range tests {
+		client := NewFakeClient(t)
+		list := New()
+		list.Store = client
+		list.AddMode = test.mode
+
+		go func() {
+			test.assert(client)
+			client.Close()
+		}()
+		if err := list.AddItem(context.Background(), "apples"); err != nil {
+			t.Fatal(err)
+		}
+		client.AssertDone()
+	}
+}
+
+func TestGroceryListAddItemsIncrementQuantity(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+	list.AddMode = AddIncrementQuantity
+
+	go func() {
+		client.AssertAll([]*Note{{ID: "1", Text: "apples", Quantity: 2}}, nil)
+		client.AssertCreateMany([]*Note{{Text: "milk", Quantity: 2}}, nil)
+		client.AssertUpdate(&Note{ID: "1", Text: "apples", Quantity: 4}, nil)
+		client.Close()
+	}()
+	if err := list.AddItems(context.Background(), []string{"apples", "milk", "apples", "milk"}); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListSummary(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{
+			{Text: "apples", Quantity: 3, Category: "produce"},
+			{Text: "pears", Quantity: 2, Unit: "lb", Category: "produce", Purchased: true},
+			{Text: "milk", Quantity: 1, Category: "dairy", Purchased: true},
+			{Text: "bread"},
+		}, nil)
+		client.Close()
+	}()
+	s, err := list.Summary(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Total != 4 || s.Purchased != 2 || s.Pending != 2 {
+		t.Errorf("expected 4 items, 2 purchased and 2 pending but was %+v", s)
+	}
+	if want := map[string]int{"": 4, "lb": 2}; !reflect.DeepEqual(s.Quantities, want) {
+		t.Errorf("expected quantities %v but was %v", want, s.Quantities)
+	}
+	want := map[string]int{"produce": 2, "dairy": 1, Uncategorized: 1}
+	if !reflect.DeepEqual(s.ByCategory, want) {
+		t.Errorf("expected categories %v but was %v", want, s.ByCategory)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListDiffTo(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+	list.DedupMode = DedupIgnoreCase
+
+	go func() {
+		client.AssertAll([]*Note{
+			{Text: "pears"},
+			{Text: "Apples"},
+			{Text: "milk"},
+			{Text: "bread"},
+		}, nil)
+		client.Close()
+	}()
+	toAdd, toRemove, err := list.DiffTo(context.Background(), []string{"milk", "eggs", "apples", "cheese", " ", "Eggs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"cheese", "eggs"}; !reflect.DeepEqual(toAdd, want) {
+		t.Errorf("expected to add %q but was %q", want, toAdd)
+	}
+	if want := []string{"bread", "pears"}; !reflect.DeepEqual(toRemove, want) {
+		t.Errorf("expected to remove %q but was %q", want, toRemove)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListApplyTarget(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	pears := &Note{ID: "1", Text: "pears"}
+	milk := &Note{ID: "2", Text: "milk"}
+	bread := &Note{ID: "3", Text: "bread"}
+	go func() {
+		client.AssertAll([]*Note{pears, milk, bread}, nil)
+		client.AssertCreateMany([]*Note{
+			{Text: "cheese", Quantity: 1},
+			{Text: "eggs", Quantity: 1},
+		}, nil)
+		client.AssertDeleteMany([]*Note{bread, pears}, nil)
+		client.Close()
+	}()
+	added, removed, err := list.ApplyTarget(context.Background(), []string{"milk", "eggs", "cheese"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 2 || removed != 2 {
+		t.Errorf("expected 2 added and 2 removed but was %d and %d", added, removed)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListApplyTargetJoinsErrors(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	pears := &Note{ID: "1", Text: "pears"}
+	milk := &Note{ID: "2", Text: "milk"}
+	boom := errors.New("boom")
+	go func() {
+		client.AssertAll([]*Note{pears, milk}, nil)
+		client.AssertCreateMany([]*Note{{Text: "eggs", Quantity: 1}}, boom)
+		client.AssertDeleteMany([]*Note{milk, pears}, &BatchError{
+			Failures: []BatchFailure{{Index: 0, Message: "locked"}},
+		})
+		client.Close()
+	}()
+	added, removed, err := list.ApplyTarget(context.Background(), []string{"eggs"})
+	if !errors.Is(err, boom) || !strings.Contains(err.Error(), `"milk": locked`) {
+		t.Error("expected both failures but was", err)
+	}
+	if added != 0 || removed != 1 {
+		t.Errorf("expected 0 added and 1 removed but was %d and %d", added, removed)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListUnits(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+	list.AddMode = AddIncrementQuantity
+
+	apples := &Note{ID: "1", Text: "apples", Quantity: 2, Unit: "lb"}
+	go func() {
+		client.AssertAll(nil, nil)
+		client.AssertCreate(&Note{Text: "apples", Quantity: 2, Unit: "lb"}, nil)
+		client.AssertAll([]*Note{apples}, nil)
+		client.AssertUpdate(&Note{ID: "1", Text: "apples", Quantity: 3, Unit: "lb"}, nil)
+		client.AssertAll([]*Note{apples}, nil)
+		client.AssertAll([]*Note{{ID: "1", Text: "apples", Quantity: 3, Unit: "lb"}, {ID: "2", Text: "milk", Quantity: 1}}, nil)
+		client.Close()
+	}()
+	if err := list.AddItemWithUnit(context.Background(), "apples", 2, "lb"); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.AddItemWithUnit(context.Background(), "apples", 1, "lb"); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.AddItem(context.Background(), "apples"); !errors.Is(err, ErrUnitMismatch) {
+		t.Fatal("expected ErrUnitMismatch but was", err)
+	}
+	items, err := list.ItemsWithQuantity(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0] != (Item{"apples", 3, "lb"}) || items[1] != (Item{"milk", 1, ""}) {
+		t.Fatalf("expected 3 lb apples and 1 milk but was %+v", items)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListPending(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{{Text: "milk", Purchased: true}, {Text: "apples"}}, nil)
+		client.Close()
+	}()
+	items, err := list.Pending(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0] != "apples" {
+		t.Fatal("expected only apples but was", items)
+	}
+
+	client.AssertDone()
+}
+
+func TestGroceryListUpdateItem(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{{ID: "7", Text: "aples", Quantity: 2}}, nil)
+		client.AssertUpdate(&Note{ID: "7", Text: "apples", Quantity: 2}, nil)
+		client.Close()
+	}()
+	if err := list.UpdateItem(context.Background(), "aples", "apples"); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListUpdateItemNormalizes(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	if err := list.UpdateItem(context.Background(), "aples", "   "); !errors.Is(err, ErrEmptyItem) {
+		t.Fatal("expected ErrEmptyItem renaming to blank but was", err)
+	}
+	go func() {
+		client.AssertAll([]*Note{{ID: "7", Text: "aples", Quantity: 2}}, nil)
+		client.AssertUpdate(&Note{ID: "7", Text: "green apples", Quantity: 2}, nil)
+		client.Close()
+	}()
+	if err := list.UpdateItem(context.Background(), "aples", "  green   apples "); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListUpdateItemMissing(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{{Text: "milk"}}, nil)
+		client.Close()
+	}()
+	if err := list.UpdateItem(context.Background(), "aples", "apples"); !errors.Is(err, ErrItemNotFound) {
+		t.Fatal("expected ErrItemNotFound but was", err)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListAppendItems(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{{Text: "milk", Order: 2}, {Text: "apples", Order: 1}, {Text: "bread", Archived: true}}, nil)
+		client.AssertAll(nil, errors.New("boom"))
+		client.Close()
+	}()
+	dst := make([]string, 1, 8)
+	dst[0] = "eggs"
+	items, err := list.AppendItems(context.Background(), dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(items, ",") != "eggs,apples,milk" {
+		t.Fatal("expected apples and milk after eggs but was", items)
+	}
+	if &items[0] != &dst[0] {
+		t.Error("expected the items appended in dst's spare capacity")
+	}
+	items, err = list.AppendItems(context.Background(), items[:1])
+	if err == nil || len(items) != 1 || items[0] != "eggs" {
+		t.Fatalf("expected dst back unchanged with an error but was %q, %v", items, err)
+	}
+
+	client.AssertDone()
+}
+
+// sliceStore is a MemoryStore whose All returns its notes without copying
+// them, so benchmarks measure the list rather than the store.
+type sliceStore struct {
+	*MemoryStore
+	notes []*Note
+}
+
+func (s *sliceStore) All(ctx context.Context) ([]*Note, error) {
+	return s.notes, nil
+}
+
+func benchmarkList() *GroceryList {
+	notes := make([]*Note, 1000)
+	for i := range notes {
+		notes[i] = &Note{ID: strconv.Itoa(i), Text: "item " + strconv.Itoa(i), Quantity: 1}
+	}
+	list := New()
+	list.Store = &sliceStore{MemoryStore: NewMemoryStore(), notes: notes}
+	return list
+}
+
+func BenchmarkGroceryListItems(b *testing.B) {
+	list := benchmarkList()
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := list.Items(context.Background()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGroceryListAppendItems(b *testing.B) {
+	list := benchmarkList()
+	var items []string
+	b.ReportAllocs()
+	for b.Loop() {
+		var err error
+		if items, err = list.AppendItems(context.Background(), items[:0]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestGroceryListItemsSorted(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{{Text: "milk"}, {Text: "Bread"}, {Text: "apples"}, {Text: "Cheese"}}, nil)
+		client.Close()
+	}()
+	items, err := list.ItemsSorted(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"apples", "Bread", "Cheese", "milk"}
+	if strings.Join(items, ",") != strings.Join(want, ",") {
+		t.Fatal("expected", want, "but was", items)
+	}
+
+	client.AssertDone()
+}
+
+func TestGroceryListItemsBy(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{{Text: "milk", Quantity: 1}, {Text: "apples", Quantity: 6}, {Text: "eggs", Quantity: 12}}, nil)
+		client.Close()
+	}()
+	items, err := list.ItemsBy(context.Background(), func(a, b *Note) bool {
+		return a.Quantity > b.Quantity
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"eggs", "apples", "milk"}
+	if strings.Join(items, ",") != strings.Join(want, ",") {
+		t.Fatal("expected", want, "but was", items)
+	}
+
+	client.AssertDone()
+}
+
+func TestGroceryListFindDuplicates(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+	list.DedupMode = DedupIgnoreCase
+
+	client.DefaultAll([]*Note{
+		{ID: "1", Text: "apples"},
+		{ID: "2", Text: "milk"},
+		{ID: "3", Text: "Apples "},
+		{ID: "4", Text: "green  apples"},
+		{ID: "5", Text: "bread"},
+		{ID: "6", Text: "APPLES"},
+		{ID: "7", Text: "green apples"},
+	}, nil)
+	groups, err := list.FindDuplicates(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := map[string]string{}
+	for key, group := range groups {
+		for _, n := range group {
+			ids[key] += n.ID
+		}
+	}
+	if want := map[string]string{"apples": "136", "green apples": "47"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("expected groups %v but was %v", want, ids)
+	}
+
+	go func() {
+		client.AssertDeleteMany([]*Note{{ID: "3", Text: "Apples "}, {ID: "6", Text: "APPLES"}, {ID: "7", Text: "green apples"}}, nil)
+		client.Close()
+	}()
+	removed, err := list.DedupeExisting(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 3 {
+		t.Error("expected 3 removed but was", removed)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListCategories(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{
+			{ID: "1", Text: "apples", Category: "produce"},
+			{ID: "2", Text: "milk", Category: "dairy"},
+			{ID: "3", Text: "batteries"},
+			{ID: "4", Text: "pears", Category: "produce"},
+			{ID: "5", Text: "tape", Category: ""},
+			{ID: "6", Text: "cake", Category: "bakery", Archived: true},
+		}, nil)
+		client.Close()
+	}()
+	categories, err := list.Categories(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"dairy", "produce", Uncategorized}; !reflect.DeepEqual(categories, want) {
+		t.Errorf("expected %q but was %q", want, categories)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListItemsByCategory(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{
+			{Text: "apples", Category: "produce"},
+			{Text: "milk", Category: "dairy"},
+			{Text: "bananas", Category: "produce"},
+			{Text: "batteries"},
+		}, nil)
+		client.Close()
+	}()
+	groups, err := list.ItemsByCategory(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 3 {
+		t.Fatal("expected three groups but was", groups)
+	}
+	if strings.Join(groups["produce"], ",") != "apples,bananas" {
+		t.Error("expected apples and bananas in produce but was", groups["produce"])
+	}
+	if strings.Join(groups["dairy"], ",") != "milk" {
+		t.Error("expected milk in dairy but was", groups["dairy"])
+	}
+	if strings.Join(groups[Uncategorized], ",") != "batteries" {
+		t.Error("expected batteries to be uncategorized but was", groups[Uncategorized])
+	}
+
+	client.AssertDone()
+}
+
+func TestGroceryListUpdateItemDuplicate(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{{ID: "1", Text: "aples"}, {ID: "2", Text: "apples"}}, nil)
+		client.Close()
+	}()
+	if err := list.UpdateItem(context.Background(), "aples", "apples"); !errors.Is(err, ErrDuplicateItem) {
+		t.Fatal("expected ErrDuplicateItem but was", err)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListItemsWhere(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	notes := []*Note{
+		{ID: "1", Text: "apples", Category: "produce"},
+		{ID: "2", Text: "milk", Category: "dairy", Purchased: true},
+		{ID: "3", Text: "pears", Category: "produce", Purchased: true},
+	}
+	client.QueueAll(allResp{notes: notes}, allResp{notes: notes})
+
+	items, err := list.ItemsWhere(context.Background(), func(n *Note) bool { return n.Purchased })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(items, ",") != "milk,pears" {
+		t.Fatal("expected milk and pears but was", items)
+	}
+	produce, err := list.NotesWhere(context.Background(), func(n *Note) bool { return n.Category == "produce" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(produce) != 2 || produce[0].ID != "1" || produce[1].ID != "3" {
+		t.Fatal("expected apples and pears but was", formatNotes(produce))
+	}
+}
+
+func TestGroceryListSearch(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{{Text: "Green Apples"}, {Text: "milk"}, {Text: "apple pie"}}, nil)
+		client.Close()
+	}()
+	items, err := list.Search(context.Background(), "APPLE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0] != "Green Apples" || items[1] != "apple pie" {
+		t.Fatal("expected both apple items but was", items)
+	}
+
+	client.AssertDone()
+}
+
+func TestGroceryListSearchEmptyQuery(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{{Text: "apples"}, {Text: "milk"}}, nil)
+		client.Close()
+	}()
+	items, err := list.Search(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatal("expected every item but was", items)
+	}
+
+	client.AssertDone()
+}
+
+func TestGroceryListSearchNoMatches(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{{Text: "apples"}}, nil)
+		client.Close()
+	}()
+	items, err := list.Search(context.Background(), "bread")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items == nil || len(items) != 0 {
+		t.Fatalf("expected an empty slice but was %#v", items)
+	}
+
+	client.AssertDone()
 }
 
-type allCall struct{}
-type allResp struct {
+type searchingStore struct {
+	API
+	query string
 	notes []*Note
-	err   error
 }
 
-func (c *FakeClient) All() ([]*Note, error) {
-	c.Calls <- &allCall{}
-	resp := (<-c.Calls).(*allResp)
-	return resp.notes, resp.err
+func (s *searchingStore) Search(ctx context.Context, query string) ([]*Note, error) {
+	s.query = query
+	return s.notes, nil
+}
+
+func TestGroceryListSearchPrefersSearcher(t *testing.T) {
+	store := &searchingStore{notes: []*Note{{Text: "apples"}}}
+	list := New()
+	list.Store = store
+
+	items, err := list.Search(context.Background(), "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.query != "app" {
+		t.Fatal("expected the store to be searched for app but was", store.query)
+	}
+	if len(items) != 1 || items[0] != "apples" {
+		t.Fatal("expected apples but was", items)
+	}
+}
+
+func TestGroceryListContains(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+	list.DedupMode = DedupIgnoreCase
+
+	go func() {
+		client.AssertAll([]*Note{{Text: "Apples"}, {Text: "milk"}}, nil)
+		client.AssertAll([]*Note{{Text: "Apples"}, {Text: "milk"}}, nil)
+		client.Close()
+	}()
+	ok, err := list.Contains(context.Background(), "apples")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Error("expected apples to be on the list")
+	}
+	ok, err = list.Contains(context.Background(), "bread")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("expected bread not to be on the list")
+	}
+	client.AssertDone()
 }
 
-func (c *FakeClient) AssertAll(notes []*Note, err error) {
-	call := (<-c.Calls).(*allCall)
-	if call == nil {
-		c.t.Error("No all call")
+func TestGroceryListContainsError(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	failed := errors.New("store is on fire")
+	go func() {
+		client.AssertAll(nil, failed)
+		client.Close()
+	}()
+	if _, err := list.Contains(context.Background(), "apples"); !errors.Is(err, failed) {
+		t.Fatal("expected store is on fire but was", err)
 	}
-	c.Calls <- &allResp{notes, err}
+	client.AssertDone()
 }
 
-type createCall struct{ note *Note }
-type createResp struct{ err error }
+type checkingStore struct {
+	API
+	text string
+}
 
-func (c *FakeClient) Create(n *Note) error {
-	c.Calls <- &createCall{n}
-	return (<-c.Calls).(*createResp).err
+func (s *checkingStore) Has(ctx context.Context, text string) (bool, error) {
+	s.text = text
+	return true, nil
 }
 
-func (c *FakeClient) AssertCreate(n *Note, err error) {
-	call := (<-c.Calls).(*createCall)
-	if *call.note != *n {
-		c.t.Error("expected create with", n, "but was", call.note)
+func TestGroceryListContainsPrefersChecker(t *testing.T) {
+	store := &checkingStore{}
+	list := New()
+	list.Store = store
+
+	ok, err := list.Contains(context.Background(), "apples")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || store.text != "apples" {
+		t.Fatal("expected the store to be checked for apples but was", store.text)
 	}
-	c.Calls <- &createResp{err}
 }
 
-func (c *FakeClient) Close() {
-	close(c.Calls)
+func TestGroceryListConcurrentUse(t *testing.T) {
+	list := New()
+	list.Store = &MemoryStore{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			if err := list.AddItem(context.Background(), fmt.Sprint("item ", i%10)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := list.Items(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	items, err := list.Items(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 10 {
+		t.Fatal("expected ten distinct items but was", len(items))
+	}
 }
 
-func (c *FakeClient) AssertDone(t *testing.T) {
-	if _, more := <-c.Calls; more {
-		t.Fatal("Did not expect more calls")
+func TestGroceryListItemsWrapsStoreError(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	storeErr := errors.New("connection refused")
+	go func() {
+		client.AssertAll(nil, storeErr)
+		client.Close()
+	}()
+	_, err := list.Items(context.Background())
+	if err == nil || err.Error() != "grocery: fetching items: connection refused" {
+		t.Fatal("expected a wrapped error but was", err)
+	}
+	if !errors.Is(err, storeErr) {
+		t.Fatal("expected the error to unwrap to the store error")
 	}
+
+	client.AssertDone()
 }
 
-func TestGroceryListCreate(t *testing.T) {
+func TestGroceryListItemsFailOpen(t *testing.T) {
+	storeErr := errors.New("connection refused")
+	for _, failOpen := range []bool{false, true} {
+		client := NewFakeClient(t)
+		list := New()
+		list.Store = client
+		list.FailOpen = failOpen
+
+		go func() {
+			client.AssertAll(nil, storeErr)
+			client.AssertAll([]*Note{{ID: "1", Text: "apples"}, {ID: "2", Text: "milk"}}, nil)
+			client.AssertAll(nil, storeErr)
+			client.Close()
+		}()
+		items, err := list.Items(context.Background())
+		if !errors.Is(err, storeErr) || items == nil || len(items) != 0 {
+			t.Fatalf("fail open %v: expected no items before any fetch succeeded but was %q, %v", failOpen, items, err)
+		}
+		if _, err := list.Items(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		items, err = list.Items(context.Background())
+		if !errors.Is(err, storeErr) {
+			t.Fatalf("fail open %v: expected the store error but was %v", failOpen, err)
+		}
+		want := ""
+		if failOpen {
+			want = "apples,milk"
+		}
+		if strings.Join(items, ",") != want {
+			t.Errorf("fail open %v: expected %q but was %q", failOpen, want, items)
+		}
+		client.AssertDone()
+	}
+}
+
+// sortingStore is a Sorter over a MemoryStore that fails with err when set.
+type sortingStore struct {
+	*MemoryStore
+	err error
+}
+
+func (s *sortingStore) AllSorted(ctx context.Context, field string, desc bool) ([]*Note, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	notes, _ := s.MemoryStore.All(ctx)
+	sort.SliceStable(notes, func(i, j int) bool { return lessItem(notes[i].Text, notes[j].Text) })
+	return notes, nil
+}
+
+func TestGroceryListItemsSortedFailOpen(t *testing.T) {
+	ctx := context.Background()
+	store := &sortingStore{MemoryStore: NewMemoryStore(Notes("milk", "apples")...)}
+	list := New()
+	list.Store = store
+	list.FailOpen = true
+
+	if _, err := list.ItemsSorted(ctx); err != nil {
+		t.Fatal(err)
+	}
+	store.err = errors.New("connection refused")
+	items, err := list.ItemsSorted(ctx)
+	if !errors.Is(err, store.err) {
+		t.Fatal("expected the store error but was", err)
+	}
+	if strings.Join(items, ",") != "apples,milk" {
+		t.Errorf("expected the last sorted items but was %q", items)
+	}
+}
+
+func TestGroceryListAddItemWrapsStoreError(t *testing.T) {
 	client := NewFakeClient(t)
 	list := New()
 	list.Store = client
 
+	storeErr := errors.New("connection refused")
 	go func() {
-		client.AssertCreate(&Note{"apples"}, nil)
+		client.AssertAll(nil, nil)
+		client.AssertCreate(&Note{Text: "apples", Quantity: 1}, storeErr)
 		client.Close()
 	}()
-	list.AddItem("apples")
-	client.AssertDone(t)
+	err := list.AddItem(context.Background(), "apples")
+	if err == nil || err.Error() != "grocery: adding item: connection refused" {
+		t.Fatal("expected a wrapped error but was", err)
+	}
+	if !errors.Is(err, storeErr) {
+		t.Fatal("expected the error to unwrap to the store error")
+	}
+
+	client.AssertDone()
 }
 
 func TestGroceryListAll(t *testing.T) {
@@ -78,10 +3548,10 @@ func TestGroceryListAll(t *testing.T) {
 	list.Store = client
 
 	go func() {
-		client.AssertAll([]*Note{{"apples"}}, nil)
+		client.AssertAll([]*Note{{Text: "apples"}}, nil)
 		client.Close()
 	}()
-	items, err := list.Items()
+	items, err := list.Items(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -92,5 +3562,312 @@ func TestGroceryListAll(t *testing.T) {
 		t.Fatal("expected apples")
 	}
 
-	client.AssertDone(t)
+	client.AssertDone()
+}
+
+func TestGroceryListItemsWithQuantity(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{{Text: "apples", Quantity: 3}, {Text: "milk", Quantity: 1}}, nil)
+		client.Close()
+	}()
+	items, err := list.ItemsWithQuantity(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatal("expected two items")
+	}
+	if items[0] != (Item{Text: "apples", Quantity: 3}) {
+		t.Fatal("expected 3 apples but was", items[0])
+	}
+	if items[1] != (Item{Text: "milk", Quantity: 1}) {
+		t.Fatal("expected 1 milk but was", items[1])
+	}
+
+	client.AssertDone()
+}
+
+func TestGroceryListHealthy(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	down := errors.New("connection refused")
+	go func() {
+		client.AssertPing(nil)
+		client.AssertPing(down)
+		client.Close()
+	}()
+	if err := list.Healthy(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.Healthy(context.Background()); !errors.Is(err, down) {
+		t.Fatal("expected connection refused but was", err)
+	}
+
+	client.AssertDone()
+}
+
+func TestGroceryListArchiveItem(t *testing.T) {
+	list := New()
+	list.Store = NewMemoryStore(&Note{Text: "milk", Quantity: 1}, &Note{Text: "apples", Quantity: 3})
+	ctx := context.Background()
+
+	if err := list.ArchiveItem(ctx, "milk"); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := list.Items(ctx); !reflect.DeepEqual(items, []string{"apples"}) {
+		t.Errorf("expected only apples but was %q", items)
+	}
+	if items, _ := list.ArchivedItems(ctx); !reflect.DeepEqual(items, []string{"milk"}) {
+		t.Errorf("expected milk to be archived but was %q", items)
+	}
+
+	if err := list.Unarchive(ctx, "milk"); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := list.Items(ctx); !reflect.DeepEqual(items, []string{"milk", "apples"}) {
+		t.Errorf("expected milk back but was %q", items)
+	}
+	if err := list.ArchiveItem(ctx, "bread"); !errors.Is(err, ErrItemNotFound) {
+		t.Error("expected ErrItemNotFound but was", err)
+	}
+}
+
+func TestGroceryListGetItems(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	apples := &Note{ID: "1", Text: "apples", Quantity: 3}
+	go func() {
+		client.AssertGetMany([]string{"apples", "bread"}, map[string]*Note{"apples": apples}, nil)
+		client.Close()
+	}()
+	notes, err := list.GetItems(context.Background(), []string{"apples", "bread"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 || notes["apples"] != apples {
+		t.Errorf("expected only apples but was %v", notes)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListClose(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = NewRetryStore(NewCachingStore(client, time.Minute), RetryPolicy{})
+	if err := list.Close(); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+
+	list.Store = struct{ API }{client}
+	if err := list.Close(); err != nil {
+		t.Fatal("expected closing a store that isn't an io.Closer to do nothing but was", err)
+	}
+}
+
+func TestGroceryListGetItem(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertGet("apples", &Note{ID: "1", Text: "apples", Quantity: 3}, nil)
+		client.AssertGet("bread", nil, ErrItemNotFound)
+		client.Close()
+	}()
+	n, err := list.GetItem(context.Background(), " apples ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !n.Equal(&Note{ID: "1", Text: "apples", Quantity: 3}) {
+		t.Fatalf("expected 3 apples but was %+v", n)
+	}
+	n, err = list.GetItem(context.Background(), "bread")
+	if err != ErrItemNotFound || n != nil {
+		t.Fatalf("expected ErrItemNotFound and no note but was %v and %+v", err, n)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListCount(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertCount(3, nil)
+		client.Close()
+	}()
+	n, err := list.Count(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatal("expected 3 but was", n)
+	}
+
+	client.AssertDone()
+}
+
+func TestGroceryListCountWithoutCounter(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+
+	go func() {
+		client.AssertAllPage(1, 0, []*Note{{Text: "apples"}}, 12, nil)
+		client.AssertAll([]*Note{{Text: "apples"}, {Text: "milk"}}, nil)
+		client.Close()
+	}()
+	list.Store = struct {
+		API
+		Pager
+	}{client, client}
+	if n, err := list.Count(context.Background()); err != nil || n != 12 {
+		t.Fatalf("expected the page total of 12 but was %d and %v", n, err)
+	}
+	list.Store = struct{ API }{client}
+	if n, err := list.Count(context.Background()); err != nil || n != 2 {
+		t.Fatalf("expected 2 notes fetched but was %d and %v", n, err)
+	}
+
+	client.AssertDone()
+}
+
+func TestGroceryListItemsPage(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAllPage(2, 2, []*Note{{Text: "bread"}, {Text: "eggs"}}, 5, nil)
+		client.Close()
+	}()
+	items, total, err := list.ItemsPage(context.Background(), 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 5 {
+		t.Fatal("expected a total of 5 but was", total)
+	}
+	if len(items) != 2 || items[0] != "bread" || items[1] != "eggs" {
+		t.Fatal("expected bread and eggs but was", items)
+	}
+
+	client.AssertDone()
+}
+
+func TestGroceryListItemsPageWithoutPager(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = struct{ API }{client}
+
+	go func() {
+		client.AssertAll([]*Note{{Text: "apples"}, {Text: "milk"}, {Text: "bread"}}, nil)
+		client.Close()
+	}()
+	items, total, err := list.ItemsPage(context.Background(), 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 {
+		t.Fatal("expected a total of 3 but was", total)
+	}
+	if len(items) != 1 || items[0] != "bread" {
+		t.Fatal("expected bread but was", items)
+	}
+
+	client.AssertDone()
+}
+
+func TestGroceryListItemsPageNegative(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = struct{ API }{client}
+
+	go func() {
+		client.AssertAll([]*Note{{Text: "apples"}, {Text: "milk"}, {Text: "bread"}}, nil)
+		client.AssertAll([]*Note{{Text: "apples"}, {Text: "milk"}, {Text: "bread"}}, nil)
+		client.Close()
+	}()
+	items, total, err := list.ItemsPage(context.Background(), 2, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || strings.Join(items, ",") != "apples,milk" {
+		t.Fatal("expected apples and milk of 3 but was", items, total)
+	}
+	items, total, err = list.ItemsPage(context.Background(), -1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || len(items) != 0 {
+		t.Fatal("expected no items of 3 but was", items, total)
+	}
+
+	client.AssertDone()
+}
+
+func TestGroceryListItemsIterator(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAllPage(2, 0, []*Note{{Text: "apples"}, {Text: "milk"}}, 3, nil)
+		client.AssertAllPage(2, 2, []*Note{{Text: "bread"}}, 3, nil)
+		client.Close()
+	}()
+	next := list.ItemsIterator(context.Background(), 2)
+	var items []string
+	for {
+		item, ok, err := next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			break
+		}
+		items = append(items, item)
+	}
+	if strings.Join(items, ",") != "apples,milk,bread" {
+		t.Fatal("expected apples, milk and bread but was", items)
+	}
+	if _, ok, _ := next(); ok {
+		t.Fatal("expected the iterator to stay done")
+	}
+
+	client.AssertDone()
+}
+
+func TestGroceryListItemsIteratorError(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	failed := errors.New("store is on fire")
+	go func() {
+		client.AssertAllPage(1, 0, []*Note{{Text: "apples"}}, 2, nil)
+		client.AssertAllPage(1, 1, nil, 0, failed)
+		client.Close()
+	}()
+	next := list.ItemsIterator(context.Background(), 1)
+	if item, ok, err := next(); item != "apples" || !ok || err != nil {
+		t.Fatal("expected apples but was", item, ok, err)
+	}
+	if _, ok, err := next(); ok || !errors.Is(err, failed) {
+		t.Fatal("expected store is on fire but was", err)
+	}
+	if _, _, err := next(); !errors.Is(err, failed) {
+		t.Fatal("expected the error to stick but was", err)
+	}
+
+	client.AssertDone()
 }
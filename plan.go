@@ -0,0 +1,62 @@
+package grocery
+
+import "context"
+
+// Plan runs op on a copy of the list, configured alike, whose store reads
+// from Store but drops every write, and returns the changes op would have
+// made. Since nothing is written, reads within op don't see op's own
+// changes. The list itself, its observers and its undo log are untouched.
+func (g *GroceryList) Plan(op func(dry *GroceryList) error) ([]ChangeEvent, error) {
+	dry := &GroceryList{
+		Store:             dryRunStore{g.Store},
+		AddMode:           g.AddMode,
+		MergeMode:         g.MergeMode,
+		AllowDuplicates:   g.AllowDuplicates,
+		DedupMode:         g.DedupMode,
+		MaxLength:         g.MaxLength,
+		MaxItems:          g.MaxItems,
+		Normalizer:        g.Normalizer,
+		Clock:             g.Clock,
+		IdempotentImports: g.IdempotentImports,
+		FailOpen:          g.FailOpen,
+	}
+	planned := []ChangeEvent{}
+	dry.OnChange(func(e ChangeEvent) {
+		planned = append(planned, e)
+	})
+	err := op(dry)
+	return planned, err
+}
+
+// dryRunStore passes reads through to the API it wraps and pretends every
+// write succeeded. It hides the optional interfaces of the wrapped store,
+// so a DeleteAll is planned as the DeleteMany it falls back to.
+type dryRunStore struct{ API }
+
+func (dryRunStore) Create(ctx context.Context, n *Note) error {
+	return nil
+}
+
+func (dryRunStore) CreateMany(ctx context.Context, notes []*Note) error {
+	return nil
+}
+
+func (dryRunStore) Update(ctx context.Context, n *Note) error {
+	return nil
+}
+
+func (dryRunStore) UpdateFields(ctx context.Context, id string, fields map[string]interface{}) error {
+	return nil
+}
+
+func (dryRunStore) Delete(ctx context.Context, n *Note) error {
+	return nil
+}
+
+func (dryRunStore) DeleteMany(ctx context.Context, notes []*Note) error {
+	return nil
+}
+
+func (dryRunStore) ReplaceAll(ctx context.Context, notes []*Note) error {
+	return nil
+}
@@ -0,0 +1,49 @@
+package grocery
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestGroceryListPlan(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+	list.UndoDepth = 5
+	observed := 0
+	list.OnChange(func(ChangeEvent) { observed++ })
+
+	client.DefaultAll([]*Note{{ID: "1", Text: "milk"}, {ID: "2", Text: "bread"}}, nil)
+	ctx := context.Background()
+	planned, err := list.Plan(func(dry *GroceryList) error {
+		if err := dry.AddItem(ctx, "apples"); err != nil {
+			return err
+		}
+		if _, _, err := dry.ApplyTarget(ctx, []string{"milk", "eggs"}); err != nil {
+			return err
+		}
+		_, _, err := dry.ImportCSV(ctx, strings.NewReader("pears\nmilk\n"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, e := range planned {
+		got = append(got, e.Op.String()+" "+e.Note.Text)
+	}
+	want := "add apples,add eggs,remove bread,add pears"
+	if strings.Join(got, ",") != want {
+		t.Errorf("expected %s but planned %s", want, strings.Join(got, ","))
+	}
+	client.AssertAllCount(3)
+	client.AssertCallCount(3)
+	if observed != 0 {
+		t.Errorf("expected the list's observers not to hear of the plan but they heard %d changes", observed)
+	}
+	if err := list.Undo(ctx); err != ErrNothingToUndo {
+		t.Error("expected nothing to undo but was", err)
+	}
+}
@@ -0,0 +1,56 @@
+package grocery
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket holding up to burst tokens, refilled at rate
+// tokens a second. Waiters that find it empty reserve a future token, so
+// they are let through in the order they arrived.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(perSecond float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{rate: perSecond, burst: float64(burst), tokens: float64(burst)}
+}
+
+// wait blocks on clock until a token is available or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context, clock Clock) error {
+	clock = clockOr(clock)
+	l.mu.Lock()
+	now := clock.Now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens--
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	select {
+	case <-clock.After(delay):
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
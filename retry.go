@@ -0,0 +1,141 @@
+package grocery
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// RetryPolicy retries failed calls up to MaxRetries times, waiting BaseDelay
+// before the first retry and doubling the wait each time after, up to
+// MaxDelay, DefaultMaxDelay when zero, unless the backend gave a wait of its
+// own in a Retry-After header. Retryable
+// decides which errors are worth retrying; when nil, transport errors,
+// ErrRequestTimeout and 429, 502, 503 and 504 responses are retried.
+//
+// Jitter randomizes the waits so that clients failing together don't retry
+// together, drawing from Rand, which returns numbers in [0, 1) and is
+// math/rand's Float64 when nil.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	Retryable  func(error) bool
+	Jitter     Jitter
+	Rand       func() float64
+}
+
+// DefaultMaxDelay is the longest a RetryPolicy waits between retries when
+// its MaxDelay is zero.
+const DefaultMaxDelay = 30 * time.Second
+
+type Jitter int
+
+const (
+	NoJitter Jitter = iota
+	// FullJitter waits anywhere from zero up to the doubled wait.
+	FullJitter
+	// DecorrelatedJitter waits anywhere from BaseDelay up to three times
+	// the previous wait.
+	DecorrelatedJitter
+)
+
+// backoff returns the wait before retry attempt+1, given the wait before
+// the last retry, prev, zero for the first.
+func (p RetryPolicy) backoff(attempt int, prev time.Duration) time.Duration {
+	random := p.Rand
+	if random == nil {
+		random = rand.Float64
+	}
+	switch p.Jitter {
+	case FullJitter:
+		return time.Duration(random() * float64(p.exponential(attempt)))
+	case DecorrelatedJitter:
+		prev = min(max(prev, p.BaseDelay), p.maxDelay())
+		return min(p.BaseDelay+time.Duration(random()*float64(3*prev-p.BaseDelay)), p.maxDelay())
+	}
+	return p.exponential(attempt)
+}
+
+// exponential returns BaseDelay doubled attempt times, stopping at the
+// maximum wait before it can overflow.
+func (p RetryPolicy) exponential(attempt int) time.Duration {
+	limit := p.maxDelay()
+	d := p.BaseDelay
+	for i := 0; i < attempt && d < limit; i++ {
+		if d > limit/2 {
+			return limit
+		}
+		d *= 2
+	}
+	return min(d, limit)
+}
+
+func (p RetryPolicy) maxDelay() time.Duration {
+	if p.MaxDelay > 0 {
+		return p.MaxDelay
+	}
+	return DefaultMaxDelay
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return transient(err)
+}
+
+func transient(err error) bool {
+	if errors.Is(err, ErrRequestTimeout) {
+		return true
+	}
+	if errors.Is(err, ErrRedirectRefused) {
+		return false
+	}
+	var he *HTTPError
+	if errors.As(err, &he) {
+		switch he.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var ue *url.Error
+	return errors.As(err, &ue)
+}
+
+// do calls fn until it succeeds, fails with an error that isn't retryable, or
+// runs out of retries, waiting on clock. It gives up early when ctx is done
+// or when the next wait would run past ctx's deadline.
+func (p RetryPolicy) do(ctx context.Context, clock Clock, fn func() error) error {
+	clock = clockOr(clock)
+	var delay time.Duration
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxRetries || !p.retryable(err) {
+			return err
+		}
+
+		delay = p.backoff(attempt, delay)
+		var he *HTTPError
+		if errors.As(err, &he) && he.RetryAfter > 0 {
+			delay = he.RetryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && deadline.Sub(clock.Now()) < delay {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-clock.After(delay):
+		}
+	}
+}
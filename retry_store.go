@@ -0,0 +1,110 @@
+package grocery
+
+import "context"
+
+// RetryStore wraps an API and retries its failed calls according to a
+// RetryPolicy. Unlike HTTPClient's own retries, retried creates get no
+// Idempotency-Key in common, so a backend can't drop the duplicates. The
+// waits between retries are timed on Clock, the wall clock when nil.
+type RetryStore struct {
+	Clock Clock
+
+	store  API
+	policy RetryPolicy
+}
+
+func NewRetryStore(store API, p RetryPolicy) *RetryStore {
+	return &RetryStore{store: store, policy: p}
+}
+
+func (s *RetryStore) Create(ctx context.Context, n *Note) error {
+	return s.policy.do(ctx, s.Clock, func() error {
+		return s.store.Create(ctx, n)
+	})
+}
+
+func (s *RetryStore) CreateMany(ctx context.Context, notes []*Note) error {
+	return s.policy.do(ctx, s.Clock, func() error {
+		return s.store.CreateMany(ctx, notes)
+	})
+}
+
+func (s *RetryStore) All(ctx context.Context) ([]*Note, error) {
+	var notes []*Note
+	err := s.policy.do(ctx, s.Clock, func() error {
+		var err error
+		notes, err = s.store.All(ctx)
+		return err
+	})
+	return notes, err
+}
+
+func (s *RetryStore) Get(ctx context.Context, text string) (*Note, error) {
+	var n *Note
+	err := s.policy.do(ctx, s.Clock, func() error {
+		var err error
+		n, err = s.store.Get(ctx, text)
+		return err
+	})
+	return n, err
+}
+
+func (s *RetryStore) GetMany(ctx context.Context, texts []string) (map[string]*Note, error) {
+	var notes map[string]*Note
+	err := s.policy.do(ctx, s.Clock, func() error {
+		var err error
+		notes, err = s.store.GetMany(ctx, texts)
+		return err
+	})
+	return notes, err
+}
+
+func (s *RetryStore) Count(ctx context.Context) (int, error) {
+	var n int
+	err := s.policy.do(ctx, s.Clock, func() error {
+		var err error
+		n, err = countNotes(ctx, s.store)
+		return err
+	})
+	return n, err
+}
+
+func (s *RetryStore) Update(ctx context.Context, n *Note) error {
+	return s.policy.do(ctx, s.Clock, func() error {
+		return s.store.Update(ctx, n)
+	})
+}
+
+func (s *RetryStore) UpdateFields(ctx context.Context, id string, fields map[string]interface{}) error {
+	return s.policy.do(ctx, s.Clock, func() error {
+		return s.store.UpdateFields(ctx, id, fields)
+	})
+}
+
+func (s *RetryStore) Delete(ctx context.Context, n *Note) error {
+	return s.policy.do(ctx, s.Clock, func() error {
+		return s.store.Delete(ctx, n)
+	})
+}
+
+func (s *RetryStore) DeleteMany(ctx context.Context, notes []*Note) error {
+	return s.policy.do(ctx, s.Clock, func() error {
+		return s.store.DeleteMany(ctx, notes)
+	})
+}
+
+func (s *RetryStore) ReplaceAll(ctx context.Context, notes []*Note) error {
+	return s.policy.do(ctx, s.Clock, func() error {
+		return s.store.ReplaceAll(ctx, notes)
+	})
+}
+
+func (s *RetryStore) Ping(ctx context.Context) error {
+	return s.policy.do(ctx, s.Clock, func() error {
+		return s.store.Ping(ctx)
+	})
+}
+
+func (s *RetryStore) Close() error {
+	return closeStore(s.store)
+}
@@ -0,0 +1,103 @@
+package grocery
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errFlaky = errors.New("flaky")
+
+// flakyStore fails the first fails calls to Create and All.
+type flakyStore struct {
+	*MemoryStore
+	fails    int
+	attempts int
+}
+
+func (s *flakyStore) fail() error {
+	s.attempts++
+	if s.attempts <= s.fails {
+		return errFlaky
+	}
+	return nil
+}
+
+func (s *flakyStore) Create(ctx context.Context, n *Note) error {
+	if err := s.fail(); err != nil {
+		return err
+	}
+	return s.MemoryStore.Create(ctx, n)
+}
+
+func (s *flakyStore) All(ctx context.Context) ([]*Note, error) {
+	if err := s.fail(); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.All(ctx)
+}
+
+func retryFlaky(err error) bool { return errors.Is(err, errFlaky) }
+
+func TestRetryStoreRetries(t *testing.T) {
+	store := &flakyStore{MemoryStore: &MemoryStore{}, fails: 2}
+	retry := NewRetryStore(store, RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, Retryable: retryFlaky})
+
+	if err := retry.Create(context.Background(), &Note{Text: "apples"}); err != nil {
+		t.Fatal(err)
+	}
+	if store.attempts != 3 {
+		t.Fatal("expected 3 attempts but was", store.attempts)
+	}
+
+	store.attempts = 0
+	notes, err := retry.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.attempts != 3 {
+		t.Fatal("expected 3 attempts but was", store.attempts)
+	}
+	if len(notes) != 1 || notes[0].Text != "apples" {
+		t.Fatal("expected apples but was", formatNotes(notes))
+	}
+}
+
+func TestRetryStoreGivesUp(t *testing.T) {
+	store := &flakyStore{MemoryStore: &MemoryStore{}, fails: 10}
+	retry := NewRetryStore(store, RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, Retryable: retryFlaky})
+
+	if _, err := retry.All(context.Background()); !errors.Is(err, errFlaky) {
+		t.Fatal("expected flaky but was", err)
+	}
+	if store.attempts != 3 {
+		t.Fatal("expected 3 attempts but was", store.attempts)
+	}
+}
+
+func TestRetryStoreNotRetryable(t *testing.T) {
+	store := &flakyStore{MemoryStore: &MemoryStore{}, fails: 10}
+	retry := NewRetryStore(store, RetryPolicy{MaxRetries: 5, BaseDelay: time.Millisecond})
+
+	if _, err := retry.All(context.Background()); !errors.Is(err, errFlaky) {
+		t.Fatal("expected flaky but was", err)
+	}
+	if store.attempts != 1 {
+		t.Fatal("expected 1 attempt but was", store.attempts)
+	}
+}
+
+func TestRetryStoreCancelled(t *testing.T) {
+	store := &flakyStore{MemoryStore: &MemoryStore{}, fails: 10}
+	retry := NewRetryStore(store, RetryPolicy{MaxRetries: 5, BaseDelay: time.Hour, Retryable: retryFlaky})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := retry.All(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatal("expected context.Canceled but was", err)
+	}
+	if store.attempts != 1 {
+		t.Fatal("expected 1 attempt but was", store.attempts)
+	}
+}
@@ -0,0 +1,298 @@
+package grocery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPClientRetriesTransientFailures(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithRetryPolicy(RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond}))
+	if err := client.Create(context.Background(), &Note{Text: "apples"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&attempts); n != 3 {
+		t.Fatal("expected three attempts but was", n)
+	}
+}
+
+func TestHTTPClientDoesNotRetryClientErrors(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithRetryPolicy(RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond}))
+	if err := client.Create(context.Background(), &Note{Text: "apples"}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if n := atomic.LoadInt32(&attempts); n != 1 {
+		t.Fatal("expected one attempt but was", n)
+	}
+}
+
+func TestHTTPClientRetryStopsWhenCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithRetryPolicy(RetryPolicy{MaxRetries: 10, BaseDelay: time.Hour}))
+	start := time.Now()
+	if err := client.Create(ctx, &Note{Text: "apples"}); err != context.Canceled {
+		t.Fatal("expected context.Canceled but was", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("expected retry to stop once cancelled")
+	}
+}
+
+func TestHTTPClientRetryAfter(t *testing.T) {
+	var mu sync.Mutex
+	var times []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		times = append(times, time.Now())
+		if len(times) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithRetryPolicy(RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond}))
+	if _, err := client.All(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(times) != 2 {
+		t.Fatalf("expected 2 attempts but there were %d", len(times))
+	}
+	if d := times[1].Sub(times[0]); d < 900*time.Millisecond || d > 1500*time.Millisecond {
+		t.Error("expected the retry a second later but it was", d)
+	}
+}
+
+func TestHTTPClientRetryAfterPastDeadline(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithRetryPolicy(RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond}))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := client.All(ctx)
+	var he *HTTPError
+	if !errors.As(err, &he) || he.RetryAfter != 30*time.Second {
+		t.Fatal("expected a 503 asking for 30s but was", err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond || atomic.LoadInt32(&attempts) != 1 {
+		t.Errorf("expected to fail fast after 1 attempt but took %v and %d", d, attempts)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"-5", 0},
+		{"soon", 0},
+		{"Fri, 01 Mar 2024 10:00:30 GMT", 30 * time.Second},
+		{"Fri, 01 Mar 2024 09:00:00 GMT", 0},
+	}
+	for _, test := range tests {
+		if got := retryAfter(test.value, now); got != test.want {
+			t.Errorf("expected Retry-After %q to wait %v but was %v", test.value, test.want, got)
+		}
+	}
+}
+
+func TestRetryPolicyJitter(t *testing.T) {
+	base := 100 * time.Millisecond
+	for _, r := range []float64{0, 0.5, 0.999} {
+		random := func() float64 { return r }
+
+		full := RetryPolicy{BaseDelay: base, Jitter: FullJitter, Rand: random}
+		for attempt := 0; attempt < 4; attempt++ {
+			d := full.backoff(attempt, 0)
+			if upper := base << attempt; d < 0 || d >= upper {
+				t.Errorf("full jitter %v, attempt %d: expected a wait in [0, %v) but was %v", r, attempt, upper, d)
+			}
+			if want := time.Duration(r * float64(base<<attempt)); d != want {
+				t.Errorf("full jitter %v, attempt %d: expected %v but was %v", r, attempt, want, d)
+			}
+		}
+
+		decorrelated := RetryPolicy{BaseDelay: base, Jitter: DecorrelatedJitter, Rand: random}
+		var prev time.Duration
+		for attempt := 0; attempt < 4; attempt++ {
+			d := decorrelated.backoff(attempt, prev)
+			if upper := 3 * max(prev, base); d < base || d >= upper {
+				t.Errorf("decorrelated jitter %v, attempt %d: expected a wait in [%v, %v) but was %v", r, attempt, base, upper, d)
+			}
+			prev = d
+		}
+	}
+
+	if d := (RetryPolicy{BaseDelay: base}).backoff(2, 0); d != 4*base {
+		t.Error("expected no jitter by default but was", d)
+	}
+}
+
+func TestRetryPolicyMaxDelay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second}
+	for _, attempt := range []int{5, 40, 70, 1000} {
+		if d := p.backoff(attempt, 0); d != DefaultMaxDelay {
+			t.Errorf("attempt %d: expected the default cap of %v but was %v", attempt, DefaultMaxDelay, d)
+		}
+	}
+
+	p.MaxDelay = 5 * time.Second
+	if d := p.backoff(2, 0); d != 4*time.Second {
+		t.Error("expected 4s below the cap but was", d)
+	}
+	if d := p.backoff(100, 0); d != 5*time.Second {
+		t.Error("expected the cap of 5s but was", d)
+	}
+	p.Jitter, p.Rand = FullJitter, func() float64 { return 0.5 }
+	if d := p.backoff(100, 0); d != 2500*time.Millisecond {
+		t.Error("expected half the cap with full jitter but was", d)
+	}
+	p.Jitter = DecorrelatedJitter
+	if d := p.backoff(100, time.Hour); d <= 0 || d > 5*time.Second {
+		t.Error("expected decorrelated jitter within the cap but was", d)
+	}
+}
+
+func TestHTTPClientIdempotencyKey(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	next := 0
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithRetryPolicy(RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond}))
+	client.IdempotencyKey = func() string {
+		next++
+		return fmt.Sprint("key-", next)
+	}
+
+	if err := client.Create(context.Background(), &Note{Text: "apples"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Create(context.Background(), &Note{Text: "milk"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"key-1", "key-1", "key-2"}
+	if strings.Join(keys, ",") != strings.Join(want, ",") {
+		t.Fatal("expected keys", want, "but was", keys)
+	}
+}
+
+func TestHTTPClientRequestTimeout(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+			return
+		}
+		w.Write([]byte(`[{"text":"apples"}]`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, WithoutTenant(),
+		WithRequestTimeout(50*time.Millisecond),
+		WithRetryPolicy(RetryPolicy{MaxRetries: 1}))
+	notes, err := client.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 || atomic.LoadInt32(&requests) != 2 {
+		t.Fatalf("expected apples on the second attempt but got %d notes after %d requests", len(notes), requests)
+	}
+}
+
+func TestHTTPClientRequestTimeoutExhausted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, WithoutTenant(), WithRequestTimeout(20*time.Millisecond))
+	start := time.Now()
+	if _, err := client.All(context.Background()); !errors.Is(err, ErrRequestTimeout) {
+		t.Fatal("expected ErrRequestTimeout but was", err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Fatal("expected the attempt to time out quickly but took", d)
+	}
+
+	// A shorter deadline on ctx still wins.
+	client.RequestTimeout = time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.All(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("expected context.DeadlineExceeded but was", err)
+	}
+}
+
+func TestNewUUID(t *testing.T) {
+	a, b := newUUID(), newUUID()
+	if len(a) != 36 || a[14] != '4' {
+		t.Error("expected a version 4 UUID but was", a)
+	}
+	if a == b {
+		t.Error("expected distinct UUIDs but both were", a)
+	}
+}
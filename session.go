@@ -0,0 +1,184 @@
+package grocery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Session collects changes to a GroceryList in memory, touching neither the
+// list nor its store, until Commit saves them together. Begin starts one.
+type Session struct {
+	list *GroceryList
+
+	mu   sync.Mutex
+	ops  []sessionOp
+	done bool
+}
+
+type sessionOp struct {
+	op      ChangeOp
+	item    string
+	newText string
+}
+
+// Begin starts a Session on the list.
+func (g *GroceryList) Begin() *Session {
+	return &Session{list: g}
+}
+
+// AddItem adds item, with a quantity of one, on Commit. Invalid items are
+// refused straight away.
+func (s *Session) AddItem(item string) error {
+	item, err := s.list.validate(item)
+	if err != nil {
+		return err
+	}
+	return s.record(sessionOp{op: ChangeAdd, item: item})
+}
+
+// RemoveItem removes item on Commit.
+func (s *Session) RemoveItem(item string) error {
+	return s.record(sessionOp{op: ChangeRemove, item: item})
+}
+
+// UpdateItem renames oldText to newText on Commit.
+func (s *Session) UpdateItem(oldText, newText string) error {
+	newText, err := s.list.validate(newText)
+	if err != nil {
+		return err
+	}
+	return s.record(sessionOp{op: ChangeUpdate, item: oldText, newText: newText})
+}
+
+func (s *Session) record(op sessionOp) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return ErrSessionDone
+	}
+	s.ops = append(s.ops, op)
+	return nil
+}
+
+// Rollback discards the session's changes.
+func (s *Session) Rollback() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ops, s.done = nil, true
+}
+
+// Commit fetches the list once, plays the session's changes over it in the
+// order they were made and saves the outcome: the removed items with one
+// DeleteMany call, the added items with one CreateMany call and the renamed
+// ones with an Update each. Changes that cancel out, such as adding and then
+// removing an item, aren't saved at all. Adding an item already on the list
+// does nothing unless duplicates are allowed.
+//
+// Nothing is saved if a change can't be played, such as removing an item
+// that isn't on the list, or if the outcome would pass MaxItems. Failed
+// saves don't stop the others, and their errors are joined. Either way the
+// session is done, and using it again fails with ErrSessionDone.
+func (s *Session) Commit(ctx context.Context) error {
+	s.mu.Lock()
+	if s.done {
+		s.mu.Unlock()
+		return ErrSessionDone
+	}
+	ops := s.ops
+	s.ops, s.done = nil, true
+	s.mu.Unlock()
+
+	return s.list.commit(ctx, ops)
+}
+
+func (g *GroceryList) commit(ctx context.Context, ops []sessionOp) error {
+	var events changes
+	defer g.notify(&events)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	notes, err := g.Store.All(ctx)
+	if err != nil {
+		return wrap("committing items", err)
+	}
+	stored := map[string]*Note{}
+	for _, n := range notes {
+		stored[n.ID] = n
+	}
+
+	// Kept notes are copies of the stored ones; added notes have no ID yet.
+	kept := cloneNotes(notes)
+	var added, removed []*Note
+	updated := map[string]*Note{}
+	current := func() []*Note {
+		return append(append([]*Note{}, kept...), added...)
+	}
+	for _, op := range ops {
+		switch op.op {
+		case ChangeAdd:
+			if !g.allowDuplicates() && g.lookup(current(), op.item) != nil {
+				continue
+			}
+			added = append(added, &Note{Text: op.item, Quantity: 1})
+		case ChangeRemove:
+			n := g.lookup(current(), op.item)
+			if n == nil {
+				return fmt.Errorf("grocery: committing items: removing %q: %w", op.item, ErrItemNotFound)
+			}
+			if n.ID == "" {
+				added = without(added, n)
+				continue
+			}
+			kept = without(kept, n)
+			delete(updated, n.ID)
+			removed = append(removed, stored[n.ID])
+		case ChangeUpdate:
+			all := current()
+			n := g.lookup(all, op.item)
+			if n == nil {
+				return fmt.Errorf("grocery: committing items: updating %q: %w", op.item, ErrItemNotFound)
+			}
+			if !g.allowDuplicates() {
+				for _, other := range all {
+					if other != n && g.same(other.Text, op.newText) {
+						return fmt.Errorf("grocery: committing items: updating %q: %w", op.item, ErrDuplicateItem)
+					}
+				}
+			}
+			n.Text = op.newText
+			if n.ID != "" {
+				updated[n.ID] = n
+			}
+		}
+	}
+	if g.MaxItems > 0 && len(kept)+len(added) > g.MaxItems {
+		return wrap("committing items", ErrListFull)
+	}
+
+	_, removeErr := g.deleteMany(ctx, "committing", removed, &events)
+	_, addErr := g.createMany(ctx, "committing", added, &events)
+	errs := []error{removeErr, addErr}
+	for _, n := range kept {
+		if updated[n.ID] == nil || n.Text == stored[n.ID].Text {
+			continue
+		}
+		if err := g.Store.Update(ctx, n); err != nil {
+			errs = append(errs, wrap("committing items", err))
+			continue
+		}
+		g.changed(&events, ChangeUpdate, n, stored[n.ID])
+	}
+	return errors.Join(errs...)
+}
+
+// without returns notes less n.
+func without(notes []*Note, n *Note) []*Note {
+	for i, other := range notes {
+		if other == n {
+			return append(notes[:i:i], notes[i+1:]...)
+		}
+	}
+	return notes
+}
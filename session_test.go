@@ -0,0 +1,82 @@
+package grocery
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSessionCommit(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	session := list.Begin()
+	for _, err := range []error{
+		session.AddItem("apples"),
+		session.AddItem("bread"),
+		session.RemoveItem("milk"),
+		session.UpdateItem("eggs", "free range eggs"),
+		session.AddItem("cake"),
+		session.RemoveItem("cake"),
+		session.AddItem("apples"),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	client.AssertNoCalls()
+
+	go func() {
+		client.AssertAll([]*Note{
+			{ID: "1", Text: "milk", Quantity: 1},
+			{ID: "2", Text: "eggs", Quantity: 12},
+		}, nil)
+		client.AssertDeleteMany([]*Note{{ID: "1", Text: "milk", Quantity: 1}}, nil)
+		client.AssertCreateMany([]*Note{{Text: "apples", Quantity: 1}, {Text: "bread", Quantity: 1}}, nil)
+		client.AssertUpdate(&Note{ID: "2", Text: "free range eggs", Quantity: 12}, nil)
+		client.Close()
+	}()
+	if err := session.Commit(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+	client.AssertCreateCount(0)
+
+	if err := session.AddItem("tea"); !errors.Is(err, ErrSessionDone) {
+		t.Error("expected ErrSessionDone after commit but was", err)
+	}
+}
+
+func TestSessionCommitFailsWhole(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	session := list.Begin()
+	session.AddItem("apples")
+	session.RemoveItem("milk")
+
+	go func() {
+		client.AssertAll(nil, nil)
+		client.Close()
+	}()
+	if err := session.Commit(context.Background()); !errors.Is(err, ErrItemNotFound) {
+		t.Fatal("expected ErrItemNotFound but was", err)
+	}
+	client.AssertDone()
+}
+
+func TestSessionRollback(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	session := list.Begin()
+	session.AddItem("apples")
+	session.Rollback()
+	if err := session.Commit(context.Background()); !errors.Is(err, ErrSessionDone) {
+		t.Error("expected ErrSessionDone after rollback but was", err)
+	}
+	client.AssertNoCalls()
+}
@@ -0,0 +1,108 @@
+package grocery
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// SplitStore sends reads to a replica and writes to the primary. Since a
+// replica lags behind, reads go to the primary too for ReadYourWrites after
+// each write, so that a caller sees its own changes; by default they never
+// do. The window is measured on Clock, the wall clock when nil. Ping and
+// Close reach both stores.
+type SplitStore struct {
+	Clock          Clock
+	ReadYourWrites time.Duration
+
+	primary API
+	replica API
+
+	mu      sync.Mutex
+	written time.Time
+}
+
+func NewSplitStore(primary, replica API) *SplitStore {
+	return &SplitStore{primary: primary, replica: replica}
+}
+
+// reader returns the store to read from.
+func (s *SplitStore) reader() API {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ReadYourWrites > 0 && !s.written.IsZero() &&
+		clockOr(s.Clock).Now().Sub(s.written) < s.ReadYourWrites {
+		return s.primary
+	}
+	return s.replica
+}
+
+// wrote starts the window of reads from the primary. A failed write counts
+// too, since it may have been applied before it failed.
+func (s *SplitStore) wrote() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.written = clockOr(s.Clock).Now()
+}
+
+func (s *SplitStore) All(ctx context.Context) ([]*Note, error) {
+	return s.reader().All(ctx)
+}
+
+func (s *SplitStore) Get(ctx context.Context, text string) (*Note, error) {
+	return s.reader().Get(ctx, text)
+}
+
+func (s *SplitStore) GetMany(ctx context.Context, texts []string) (map[string]*Note, error) {
+	return s.reader().GetMany(ctx, texts)
+}
+
+func (s *SplitStore) Count(ctx context.Context) (int, error) {
+	return countNotes(ctx, s.reader())
+}
+
+func (s *SplitStore) Create(ctx context.Context, n *Note) error {
+	defer s.wrote()
+	return s.primary.Create(ctx, n)
+}
+
+func (s *SplitStore) CreateMany(ctx context.Context, notes []*Note) error {
+	defer s.wrote()
+	return s.primary.CreateMany(ctx, notes)
+}
+
+func (s *SplitStore) Update(ctx context.Context, n *Note) error {
+	defer s.wrote()
+	return s.primary.Update(ctx, n)
+}
+
+func (s *SplitStore) UpdateFields(ctx context.Context, id string, fields map[string]interface{}) error {
+	defer s.wrote()
+	return s.primary.UpdateFields(ctx, id, fields)
+}
+
+func (s *SplitStore) Delete(ctx context.Context, n *Note) error {
+	defer s.wrote()
+	return s.primary.Delete(ctx, n)
+}
+
+func (s *SplitStore) DeleteMany(ctx context.Context, notes []*Note) error {
+	defer s.wrote()
+	return s.primary.DeleteMany(ctx, notes)
+}
+
+func (s *SplitStore) ReplaceAll(ctx context.Context, notes []*Note) error {
+	defer s.wrote()
+	return s.primary.ReplaceAll(ctx, notes)
+}
+
+func (s *SplitStore) Ping(ctx context.Context) error {
+	return errors.Join(s.primary.Ping(ctx), s.replica.Ping(ctx))
+}
+
+func (s *SplitStore) Close() error {
+	return errors.Join(closeStore(s.primary), closeStore(s.replica))
+}
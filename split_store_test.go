@@ -0,0 +1,64 @@
+package grocery
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSplitStoreRoutesReadsAndWrites(t *testing.T) {
+	primary := NewFakeClient(t)
+	replica := NewFakeClient(t)
+	list := New()
+	list.Store = NewSplitStore(primary, replica)
+
+	go func() {
+		replica.AssertAll(nil, nil)
+		replica.AssertAll([]*Note{{ID: "1", Text: "apples", Quantity: 1}}, nil)
+		replica.Close()
+	}()
+	go func() {
+		primary.AssertCreate(&Note{Text: "apples", Quantity: 1}, nil)
+		primary.Close()
+	}()
+	if err := list.AddItem(context.Background(), "apples"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := list.Items(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	primary.AssertDone()
+	replica.AssertDone()
+}
+
+func TestSplitStoreReadYourWrites(t *testing.T) {
+	ctx := context.Background()
+	primary := NewFakeClient(t)
+	replica := NewFakeClient(t)
+	clock := newFakeClock()
+	store := NewSplitStore(primary, replica)
+	store.Clock = clock
+	store.ReadYourWrites = time.Second
+
+	go func() {
+		primary.AssertCreate(&Note{Text: "apples"}, nil)
+		primary.AssertAll([]*Note{{ID: "1", Text: "apples"}}, nil)
+		primary.Close()
+	}()
+	go func() {
+		replica.AssertAll(nil, nil)
+		replica.Close()
+	}()
+	if err := store.Create(ctx, &Note{Text: "apples"}); err != nil {
+		t.Fatal(err)
+	}
+	if notes, err := store.All(ctx); err != nil || len(notes) != 1 {
+		t.Fatalf("expected apples from the primary but was %v, %v", notes, err)
+	}
+	clock.Advance(time.Second)
+	if notes, err := store.All(ctx); err != nil || len(notes) != 0 {
+		t.Fatalf("expected nothing from the replica but was %v, %v", notes, err)
+	}
+	primary.AssertDone()
+	replica.AssertDone()
+}
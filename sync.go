@@ -0,0 +1,145 @@
+package grocery
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// syncState is the background sync started by StartSync. g.mu guards it.
+type syncState struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	// notes is the latest fetch, or nil when there is none or a change has
+	// been made since.
+	notes []*Note
+	// tenant is the tenant of the ctx the sync fetches with, the only one
+	// whose calls are served from notes.
+	tenant string
+	// gen counts changes, so that a fetch that raced one isn't kept.
+	gen int
+}
+
+// StartSync fetches every note from the store now and then every interval on
+// Clock until ctx is done or Stop is called, and serves Items, ItemsSorted,
+// ItemsBy and RecentItems from the latest fetch. Only calls for the tenant
+// of ctx from ContextWithTenant are served from it; calls for any other
+// tenant read the store as though there were no sync. A change made through
+// the list drops the fetched notes, so that those calls read the store again
+// until the next fetch. A failed fetch keeps the notes from the one before
+// and is reported to SyncError.
+// Starting a sync stops any other.
+func (g *GroceryList) StartSync(ctx context.Context, interval time.Duration) {
+	g.Stop()
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	g.mu.Lock()
+	g.sync.cancel, g.sync.done = cancel, done
+	g.sync.tenant = TenantFromContext(ctx)
+	clock := clockOr(g.Clock)
+	g.mu.Unlock()
+
+	go func() {
+		defer close(done)
+
+		g.refresh(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-clock.After(interval):
+				g.refresh(ctx)
+			}
+		}
+	}()
+}
+
+// Stop ends the sync started by StartSync, waiting for a fetch in progress,
+// and drops the fetched notes. It does nothing if there is no sync.
+func (g *GroceryList) Stop() {
+	g.mu.Lock()
+	cancel, done := g.sync.cancel, g.sync.done
+	g.sync.cancel, g.sync.done = nil, nil
+	g.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.sync.notes = nil
+}
+
+// refresh fetches every note for the sync, unless a change is made or the
+// sync stopped meanwhile, and reports a failed fetch to SyncError.
+func (g *GroceryList) refresh(ctx context.Context) {
+	g.mu.RLock()
+	gen := g.sync.gen
+	report := g.SyncError
+	g.mu.RUnlock()
+
+	notes, err := g.Store.All(ctx)
+	if err != nil {
+		if report != nil && ctx.Err() == nil {
+			report(wrap("syncing items", err))
+		}
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if gen == g.sync.gen && ctx.Err() == nil {
+		g.sync.notes = cloneNotes(notes)
+	}
+}
+
+// lastGood is the latest successful fetch of the items for each tenant from
+// ContextWithTenant, kept for FailOpen. It has a lock of its own as it is set
+// by reads, which only hold g.mu for reading.
+type lastGood struct {
+	mu    sync.Mutex
+	notes map[string][]*Note
+}
+
+func (l *lastGood) get(ctx context.Context) []*Note {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.notes[TenantFromContext(ctx)]
+}
+
+func (l *lastGood) set(ctx context.Context, notes []*Note) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.notes == nil {
+		l.notes = map[string][]*Note{}
+	}
+	l.notes[TenantFromContext(ctx)] = notes
+}
+
+// synced returns the sync's latest fetch if there is one for the tenant of
+// ctx, or nil. g.mu must be held.
+func (g *GroceryList) synced(ctx context.Context) []*Note {
+	if TenantFromContext(ctx) != g.sync.tenant {
+		return nil
+	}
+	return g.sync.notes
+}
+
+// all returns every note, from the sync's latest fetch if there is one for
+// the tenant of ctx. g.mu must be held.
+func (g *GroceryList) all(ctx context.Context) ([]*Note, error) {
+	if notes := g.synced(ctx); notes != nil {
+		return cloneNotes(notes), nil
+	}
+	return g.Store.All(ctx)
+}
+
+// invalidate drops the sync's fetched notes after a change. g.mu must be
+// held.
+func (g *GroceryList) invalidate() {
+	g.sync.gen++
+	g.sync.notes = nil
+}
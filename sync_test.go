@@ -0,0 +1,212 @@
+package grocery
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// synced waits for the list to hold a fetch with want as its items.
+func synced(t *testing.T, list *GroceryList, want []string) {
+	t.Helper()
+	deadline := time.Now().Add(defaultFakeTimeout)
+	for time.Now().Before(deadline) {
+		list.mu.RLock()
+		notes := list.sync.notes
+		list.mu.RUnlock()
+		if notes != nil {
+			items := make([]string, len(notes))
+			for i, n := range notes {
+				items[i] = n.Text
+			}
+			if reflect.DeepEqual(items, want) {
+				return
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected a sync of %q within %v", want, defaultFakeTimeout)
+}
+
+func TestGroceryListSync(t *testing.T) {
+	store := NewMemoryStore(&Note{Text: "apples", Quantity: 1})
+	list := New()
+	list.Store = store
+	clock := newFakeClock()
+	list.Clock = clock
+	ctx := context.Background()
+
+	list.StartSync(ctx, time.Minute)
+	synced(t, list, []string{"apples"})
+	clock.waitForWaiters(t, 1)
+
+	// Changed behind the list's back, so only a sync can tell.
+	store.Create(ctx, &Note{Text: "milk", Quantity: 1})
+	if items, _ := list.Items(ctx); !reflect.DeepEqual(items, []string{"apples"}) {
+		t.Errorf("expected the synced apples but was %q", items)
+	}
+	clock.Advance(time.Minute - time.Second)
+	if items, _ := list.Items(ctx); !reflect.DeepEqual(items, []string{"apples"}) {
+		t.Errorf("expected no fetch before the minute is up but was %q", items)
+	}
+	clock.Advance(time.Second)
+	synced(t, list, []string{"apples", "milk"})
+	if items, _ := list.Items(ctx); !reflect.DeepEqual(items, []string{"apples", "milk"}) {
+		t.Errorf("expected apples and milk after the tick but was %q", items)
+	}
+
+	// A change through the list is seen at once.
+	if err := list.RemoveItem(ctx, "apples"); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := list.Items(ctx); !reflect.DeepEqual(items, []string{"milk"}) {
+		t.Errorf("expected only milk after removing apples but was %q", items)
+	}
+
+	list.Stop()
+	list.Stop()
+}
+
+func TestGroceryListSyncMaxItems(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+	list.AllowDuplicates = true
+	list.MaxItems = 1
+	list.Clock = newFakeClock()
+
+	go client.AssertAll([]*Note{{ID: "1", Text: "apples"}}, nil)
+	list.StartSync(context.Background(), time.Minute)
+	synced(t, list, []string{"apples"})
+
+	if err := list.AddItem(context.Background(), "milk"); !errors.Is(err, ErrListFull) {
+		t.Error("expected ErrListFull without a Count call but was", err)
+	}
+	list.Stop()
+	client.AssertCallCount(1)
+}
+
+func TestGroceryListSyncStopsWithContext(t *testing.T) {
+	list := New()
+	list.Store = NewMemoryStore()
+	list.Clock = newFakeClock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	list.StartSync(ctx, time.Minute)
+	list.mu.RLock()
+	done := list.sync.done
+	list.mu.RUnlock()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(defaultFakeTimeout):
+		t.Fatal("expected cancelling ctx to stop the sync")
+	}
+	list.Stop()
+}
+
+func TestGroceryListSyncUndo(t *testing.T) {
+	ctx := context.Background()
+	list := New()
+	list.Store = NewMemoryStore()
+	list.UndoDepth = 10
+	list.Clock = newFakeClock()
+	if err := list.AddItem(ctx, "apples"); err != nil {
+		t.Fatal(err)
+	}
+
+	list.StartSync(ctx, time.Minute)
+	defer list.Stop()
+	synced(t, list, []string{"apples"})
+	if err := list.Undo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := list.Items(ctx); len(items) != 0 {
+		t.Errorf("expected no items after undoing the add but was %q", items)
+	}
+}
+
+// tenantStore keeps a list of notes for each tenant from ContextWithTenant,
+// failing every All while fail is set.
+type tenantStore struct {
+	API
+	mu    sync.Mutex
+	notes map[string][]*Note
+	fail  bool
+}
+
+func (s *tenantStore) All(ctx context.Context) ([]*Note, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return nil, errFlaky
+	}
+	return cloneNotes(s.notes[TenantFromContext(ctx)]), nil
+}
+
+func (s *tenantStore) setFail(fail bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail = fail
+}
+
+func TestGroceryListTenantsDontShare(t *testing.T) {
+	store := &tenantStore{notes: map[string][]*Note{
+		"a": {{Text: "apples"}},
+		"b": {{Text: "milk"}},
+	}}
+	list := New()
+	list.Store = store
+	list.FailOpen = true
+	list.Clock = newFakeClock()
+	a := ContextWithTenant(context.Background(), "a")
+	b := ContextWithTenant(context.Background(), "b")
+
+	if items, err := list.Items(a); err != nil || !reflect.DeepEqual(items, []string{"apples"}) {
+		t.Fatalf("expected apples for a but was %q, %v", items, err)
+	}
+	store.setFail(true)
+	if items, err := list.Items(b); !errors.Is(err, errFlaky) || len(items) != 0 {
+		t.Errorf("expected no items for b without a fetch of its own but was %q, %v", items, err)
+	}
+	store.setFail(false)
+
+	list.StartSync(a, time.Minute)
+	defer list.Stop()
+	synced(t, list, []string{"apples"})
+	if items, err := list.Items(b); err != nil || !reflect.DeepEqual(items, []string{"milk"}) {
+		t.Errorf("expected milk for b rather than a's sync but was %q, %v", items, err)
+	}
+}
+
+func TestGroceryListSyncError(t *testing.T) {
+	store := &tenantStore{notes: map[string][]*Note{"": {{Text: "apples"}}}}
+	list := New()
+	list.Store = store
+	clock := newFakeClock()
+	list.Clock = clock
+	errs := make(chan error, 1)
+	list.SyncError = func(err error) { errs <- err }
+
+	list.StartSync(context.Background(), time.Minute)
+	defer list.Stop()
+	synced(t, list, []string{"apples"})
+
+	store.setFail(true)
+	clock.waitForWaiters(t, 1)
+	clock.Advance(time.Minute)
+	select {
+	case err := <-errs:
+		if !errors.Is(err, errFlaky) {
+			t.Error("expected the fetch's error but was", err)
+		}
+	case <-time.After(defaultFakeTimeout):
+		t.Fatal("expected the failed fetch to be reported")
+	}
+	if items, _ := list.Items(context.Background()); !reflect.DeepEqual(items, []string{"apples"}) {
+		t.Errorf("expected the last good sync of apples but was %q", items)
+	}
+}
@@ -0,0 +1,70 @@
+package grocery
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// FormatText writes the unarchived items to w as plain text for sharing,
+// grouped under their sorted categories with Uncategorized last:
+//
+//	produce:
+//	  - 3 apples
+//	  - 2 lb pears
+//	  - bananas
+//
+// Items are sorted within a group, and a quantity of 1 or none is left out.
+func (g *GroceryList) FormatText(ctx context.Context, w io.Writer) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	notes, err := g.Store.All(ctx)
+	if err != nil {
+		return wrap("formatting items", err)
+	}
+
+	groups := map[string][]*Note{}
+	for _, n := range unarchived(notes) {
+		category := n.Category
+		if category == "" {
+			category = Uncategorized
+		}
+		groups[category] = append(groups[category], n)
+	}
+	categories := make([]string, 0, len(groups))
+	for category := range groups {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		a, b := categories[i], categories[j]
+		if (a == Uncategorized) != (b == Uncategorized) {
+			return b == Uncategorized
+		}
+		return lessItem(a, b)
+	})
+
+	var b strings.Builder
+	for _, category := range categories {
+		group := groups[category]
+		sort.SliceStable(group, func(i, j int) bool { return lessItem(group[i].Text, group[j].Text) })
+		fmt.Fprintf(&b, "%s:\n", category)
+		for _, n := range group {
+			fmt.Fprintf(&b, "  - %s\n", textItem(n))
+		}
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+func textItem(n *Note) string {
+	switch {
+	case n.Unit != "":
+		return fmt.Sprintf("%d %s %s", n.Quantity, n.Unit, n.Text)
+	case n.Quantity > 1:
+		return fmt.Sprintf("%d %s", n.Quantity, n.Text)
+	}
+	return n.Text
+}
@@ -0,0 +1,40 @@
+package grocery
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestGroceryListFormatText(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+
+	go func() {
+		client.AssertAll([]*Note{
+			{ID: "1", Text: "bananas", Quantity: 1, Category: "produce"},
+			{ID: "2", Text: "batteries", Quantity: 4},
+			{ID: "3", Text: "apples", Quantity: 3, Category: "produce"},
+			{ID: "4", Text: "milk", Quantity: 2, Unit: "l", Category: "dairy"},
+			{ID: "5", Text: "candles", Quantity: 1, Archived: true},
+		}, nil)
+		client.Close()
+	}()
+	var b strings.Builder
+	if err := list.FormatText(context.Background(), &b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "dairy:\n" +
+		"  - 2 l milk\n" +
+		"produce:\n" +
+		"  - 3 apples\n" +
+		"  - bananas\n" +
+		"uncategorized:\n" +
+		"  - 4 batteries\n"
+	if b.String() != want {
+		t.Fatalf("expected\n%s\nbut was\n%s", want, b.String())
+	}
+	client.AssertDone()
+}
@@ -0,0 +1,84 @@
+package grocery
+
+import "context"
+
+// undoEntry is a logged change and what Undo needs to reverse it.
+type undoEntry struct {
+	op   ChangeOp
+	note *Note
+	prev *Note
+}
+
+// logUndo logs a change, dropping the oldest beyond g.UndoDepth. A clear
+// can't be reversed, so it empties the log. An add the store gave no ID
+// can't be deleted again, so it isn't logged. g.mu must be held.
+func (g *GroceryList) logUndo(op ChangeOp, n, prev *Note) {
+	if g.UndoDepth <= 0 || op == ChangeAdd && n.ID == "" {
+		return
+	}
+	if op == ChangeClear {
+		g.undo = nil
+		return
+	}
+	e := undoEntry{op: op, note: cloneNote(n)}
+	if prev != nil {
+		e.prev = cloneNote(prev)
+	}
+	g.undo = append(g.undo, e)
+	if len(g.undo) > g.UndoDepth {
+		g.undo = g.undo[len(g.undo)-g.UndoDepth:]
+	}
+}
+
+// Undo reverses the latest logged change: an added item is deleted, a
+// removed item is created again and an updated item is put back as it was.
+// It returns ErrNothingToUndo when the log is empty. A change that fails to
+// be reversed stays in the log.
+func (g *GroceryList) Undo(ctx context.Context) error {
+	var events changes
+	defer g.notify(&events)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.undo) == 0 {
+		return ErrNothingToUndo
+	}
+	e := g.undo[len(g.undo)-1]
+
+	switch e.op {
+	case ChangeAdd:
+		if err := g.Store.Delete(ctx, e.note); err != nil {
+			return wrap("undoing add", err)
+		}
+		events.add(ChangeRemove, e.note)
+	case ChangeRemove:
+		n := cloneNote(e.note)
+		n.ID = ""
+		if err := g.Store.Create(ctx, n); err != nil {
+			return wrap("undoing remove", err)
+		}
+		// Earlier changes to the note now refer to its new ID.
+		oldID := e.note.ID
+		for _, older := range g.undo[:len(g.undo)-1] {
+			if oldID != "" && older.note.ID == oldID {
+				older.note.ID = n.ID
+				if older.prev != nil {
+					older.prev.ID = n.ID
+				}
+			}
+		}
+		events.add(ChangeAdd, n)
+	case ChangeUpdate:
+		// The note is put back as it was but at its current version, so a
+		// versioned store takes the update.
+		prev := cloneNote(e.prev)
+		prev.Version = e.note.Version
+		if err := g.Store.Update(ctx, prev); err != nil {
+			return wrap("undoing update", err)
+		}
+		events.add(ChangeUpdate, prev)
+	}
+	g.invalidate()
+	g.undo = g.undo[:len(g.undo)-1]
+	return nil
+}
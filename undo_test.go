@@ -0,0 +1,204 @@
+package grocery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestGroceryListUndoAdd(t *testing.T) {
+	client := NewFakeClient(t)
+	list := New()
+	list.Store = client
+	list.UndoDepth = 10
+
+	go func() {
+		client.AssertAll(nil, nil)
+		client.AssertCreateWithID(&Note{Text: "apples", Quantity: 1}, "1", nil)
+		client.AssertAll([]*Note{{ID: "1", Text: "apples", Quantity: 1}}, nil)
+		client.AssertCreateWithID(&Note{Text: "milk", Quantity: 1}, "2", nil)
+		client.AssertDelete(&Note{ID: "2", Text: "milk", Quantity: 1}, nil)
+		client.Close()
+	}()
+	for _, item := range []string{"apples", "milk"} {
+		if err := list.AddItem(context.Background(), item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := list.Undo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	client.AssertDone()
+}
+
+func TestGroceryListUndo(t *testing.T) {
+	ctx := context.Background()
+	list := New()
+	list.Store = &MemoryStore{}
+	list.UndoDepth = 10
+
+	for _, item := range []string{"apples", "milk", "bread"} {
+		if err := list.AddItem(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := list.MarkPurchased(ctx, "milk"); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.RemoveItem(ctx, "milk"); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.RemoveItem(ctx, "bread"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Bread comes back, then purchased milk, which is then unpurchased.
+	for i := 0; i < 3; i++ {
+		if err := list.Undo(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	items, err := list.Items(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(items, ",") != "apples,bread,milk" {
+		t.Fatal("expected apples, bread and milk but was", items)
+	}
+	pending, err := list.Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 3 {
+		t.Fatal("expected milk to be unpurchased but pending was", pending)
+	}
+}
+
+func TestGroceryListUndoDepth(t *testing.T) {
+	ctx := context.Background()
+	list := New()
+	list.Store = &MemoryStore{}
+	list.UndoDepth = 1
+
+	for _, item := range []string{"apples", "milk"} {
+		if err := list.AddItem(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := list.Undo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.Undo(ctx); !errors.Is(err, ErrNothingToUndo) {
+		t.Fatal("expected ErrNothingToUndo but was", err)
+	}
+	items, err := list.Items(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(items, ",") != "apples" {
+		t.Fatal("expected only apples but was", items)
+	}
+}
+
+func TestGroceryListUndoUpdateVersioned(t *testing.T) {
+	var mu sync.Mutex
+	note := Note{ID: "1", Text: "apples", Quantity: 1, Version: 1}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodGet {
+			json.NewEncoder(w).Encode([]Note{note})
+			return
+		}
+		if match := r.Header.Get("If-Match"); match != strconv.Quote(strconv.Itoa(note.Version)) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		var n Note
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Error(err)
+		}
+		n.Version = note.Version + 1
+		note = n
+		json.NewEncoder(w).Encode(note)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	list := New()
+	list.Store = &HTTPClient{BaseURL: server.URL, NoTenant: true}
+	list.UndoDepth = 10
+	if err := list.MarkPurchased(ctx, "apples"); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.Undo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if note.Purchased || note.Version != 3 {
+		t.Errorf("expected apples unpurchased at version 3 but was %+v", note)
+	}
+}
+
+func TestGroceryListUndoAddItemsHTTP(t *testing.T) {
+	var mu sync.Mutex
+	notes := map[string]*Note{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/notes":
+			all := []*Note{}
+			for _, n := range notes {
+				all = append(all, n)
+			}
+			json.NewEncoder(w).Encode(all)
+		case r.Method == http.MethodPost && r.URL.Path == "/notes/batch":
+			var created []*Note
+			json.NewDecoder(r.Body).Decode(&created)
+			for _, n := range created {
+				n.ID = strconv.Itoa(len(notes) + 1)
+				notes[n.ID] = n
+			}
+			json.NewEncoder(w).Encode(map[string][]*Note{"notes": created})
+		case r.Method == http.MethodDelete:
+			id := strings.TrimPrefix(r.URL.Path, "/notes/")
+			if notes[id] == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			delete(notes, id)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	list := New()
+	list.Store = NewHTTPClient(server.URL, WithoutTenant())
+	list.UndoDepth = 10
+	if err := list.AddItems(ctx, []string{"apples", "milk"}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := list.Undo(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := list.Undo(ctx); !errors.Is(err, ErrNothingToUndo) {
+		t.Fatal("expected ErrNothingToUndo but was", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(notes) != 0 {
+		t.Errorf("expected undo to delete both items but %d are left", len(notes))
+	}
+}